@@ -5,8 +5,6 @@ import (
 	"crypto/rand"
 	"encoding/binary"
 	"errors"
-
-	"github.com/cossacklabs/acra/utils"
 )
 
 // getDataLengthFromAcraStruct unpack data length value from AcraStruct
@@ -73,13 +71,15 @@ func CreateAcrastruct(data []byte, acraPublic *PublicECKey, context []byte) ([]b
 	if err != nil {
 		return nil, err
 	}
+	defer randomKeyPair.Private.Zeroize()
 	publicKey, err := randomKeyPair.Public.Marshal()
 	if err != nil {
 		return nil, err
 	}
 	// generate random symmetric key
-	randomKey := make([]byte, SymmetricKeySize)
-	n, err := rand.Read(randomKey)
+	randomKey := NewSecretBytes(SymmetricKeySize)
+	defer randomKey.Destroy()
+	n, err := rand.Read(randomKey.Bytes())
 	if err != nil {
 		return nil, err
 	}
@@ -92,18 +92,16 @@ func CreateAcrastruct(data []byte, acraPublic *PublicECKey, context []byte) ([]b
 	if err != nil {
 		return nil, err
 	}
-	encryptedKey, err := smessage.Wrap(randomKey)
+	encryptedKey, err := smessage.Wrap(randomKey.Bytes())
 	if err != nil {
 		return nil, err
 	}
-	randomKeyPair.Private.Zeroize()
 
 	// create scell for encrypting data
-	encryptedData, err := CellSealEncrypt(randomKey, data, nil)
+	encryptedData, err := CellSealEncrypt(randomKey.Bytes(), data, nil)
 	if err != nil {
 		return nil, err
 	}
-	Zeroize(randomKey)
 
 	// pack acrastruct
 	dateLength := make([]byte, DataLengthSize)
@@ -131,10 +129,12 @@ func DecryptAcrastruct(data []byte, privateKey *PrivateECKey, zone []byte) ([]by
 	if err != nil {
 		return nil, err
 	}
-	symmetricKey, err := smessage.Unwrap(innerData[PublicKeyLength:KeyBlockLength])
+	decryptedKey, err := smessage.Unwrap(innerData[PublicKeyLength:KeyBlockLength])
 	if err != nil {
 		return []byte{}, err
 	}
+	symmetricKey := NewSecretBytesFromBytes(decryptedKey)
+	defer symmetricKey.Destroy()
 	//
 	var length uint64
 	// convert from little endian
@@ -143,8 +143,7 @@ func DecryptAcrastruct(data []byte, privateKey *PrivateECKey, zone []byte) ([]by
 		return []byte{}, err
 	}
 
-	decrypted, err := CellSealDecrypt(symmetricKey, innerData[KeyBlockLength+DataLengthSize:], zone)
-	utils.ZeroizeBytes(symmetricKey)
+	decrypted, err := CellSealDecrypt(symmetricKey.Bytes(), innerData[KeyBlockLength+DataLengthSize:], zone)
 	if err != nil {
 		return nil, err
 	}
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
-	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/binary"
@@ -39,26 +38,69 @@ func themisKDF(key, label []byte, contexts [][]byte) []byte {
 		}
 		key = implicitKey
 	}
-	hmacHash := hmac.New(sha256.New, key)
-	hmacHash.Write(out[:4])
-	hmacHash.Write(label)
-	hmacHash.Write(out[:1])
-	for _, context := range contexts {
-		if len(context) > 0 {
-			hmacHash.Write(context)
-		}
+	pads := newHMACPads(key)
+	defer pads.Destroy()
+	parts := append([][]byte{out[:4], label, out[:1]}, contexts...)
+	return hmacSumWithPads(sha256.New(), pads.Bytes(), nil, parts...)
+}
+
+const (
+	hmacInnerPad = 0x36
+	hmacOuterPad = 0x5c
+)
+
+// newHMACPads return HMAC-SHA256 inner and outer padded key blocks as RFC 2104 defines: keys longer than block
+// hashed, shorter padded with zeros. Kept in SecretBytes instead of crypto/hmac instance so they can be wiped
+func newHMACPads(key []byte) *SecretBytes {
+	pads := NewSecretBytes(2 * sha256.BlockSize)
+	data := pads.Bytes()
+	if len(key) > sha256.BlockSize {
+		digest := sha256.Sum256(key)
+		copy(data, digest[:])
+		Zeroize(digest[:])
+	} else {
+		copy(data, key)
+	}
+	copy(data[sha256.BlockSize:], data[:sha256.BlockSize])
+	for i := 0; i < sha256.BlockSize; i++ {
+		data[i] ^= hmacInnerPad
+		data[sha256.BlockSize+i] ^= hmacOuterPad
 	}
-	return hmacHash.Sum(nil)
+	return pads
+}
+
+// hmacSumWithPads append HMAC-SHA256 of parts with pads from newHMACPads to output using SHA-256 instance h. h reset
+// before return so it doesn't keep state derived from key. Parts may be stored in output's spare capacity
+func hmacSumWithPads(h hash.Hash, pads []byte, output []byte, parts ...[]byte) []byte {
+	h.Write(pads[:sha256.BlockSize])
+	for _, part := range parts {
+		h.Write(part)
+	}
+	inner := h.Sum(output)
+	h.Reset()
+	h.Write(pads[sha256.BlockSize:])
+	h.Write(inner[len(output):])
+	result := h.Sum(output)
+	h.Reset()
+	return result
 }
 
 var THEMIS_SYM_KDF_KEY_LABEL = []byte("Themis secure cell message key")
+var THEMIS_SYM_KDF_IV_LABEL = []byte("Themis secure cell message iv")
 
 const (
+	SOTER_SYM_128_KEY_LENGTH uint32 = 0x00000080
+	SOTER_SYM_192_KEY_LENGTH uint32 = 0x000000c0
 	SOTER_SYM_256_KEY_LENGTH uint32 = 0x00000100
+	SOTER_SYM_AES_CTR        uint32 = 0x20000000
 	SOTER_SYM_AES_GCM        uint32 = 0x40010000
 	SOTER_SYM_KDF_MASK       uint32 = 0x0f000000
 	SOTER_SYM_NOKDF          uint32 = 0x00000000
 
+	SOTER_SYM_ALG_MASK        uint32 = 0xf0000000
+	SOTER_SYM_PADDING_MASK    uint32 = 0x000f0000
+	SOTER_SYM_KEY_LENGTH_MASK uint32 = 0x00000fff
+
 	SOTER_SYM_PBKDF2 uint32 = 0x01000000
 
 	THEMIS_AUTH_SYM_KEY_LENGTH      uint32 = SOTER_SYM_256_KEY_LENGTH
@@ -66,6 +108,10 @@ const (
 	THEMIS_AUTH_SYM_IV_LENGTH              = 12
 	THEMIS_AUTH_SYM_AUTH_TAG_LENGTH        = 16
 	SOTER_SYM_MAX_KEY_LENGTH               = 128
+
+	THEMIS_SYM_KEY_LENGTH uint32 = SOTER_SYM_256_KEY_LENGTH
+	THEMIS_SYM_ALG        uint32 = SOTER_SYM_AES_CTR | THEMIS_SYM_KEY_LENGTH
+	THEMIS_SYM_IV_LENGTH         = 16
 )
 
 const authSymMessageHeaderFieldsSize = (32 / 8) * 4
@@ -126,31 +172,51 @@ func (hdr *AuthSymMessageHeader) Marshal() ([]byte, error) {
 	return output.Bytes(), nil
 }
 
+// Errors returned on decryption of Secure Cell with incorrect structure or data
+var (
+	// ErrCellTruncated returned when data shorter than header or message length declared in header
+	ErrCellTruncated = ThemisError("Secure Cell data truncated")
+	// ErrCellMalformed returned when header has incorrect IV or auth tag length or data longer than declared
+	ErrCellMalformed = ThemisError("Secure Cell data malformed")
+	// ErrCellAuthFailed returned when data can't be authenticated with key and context
+	ErrCellAuthFailed = ThemisError("Secure Cell authentication failed")
+)
+
+// UnmarshalAuthSymMessageHeader parse header from data. Accepts only THEMIS_AUTH_SYM_IV_LENGTH and
+// THEMIS_AUTH_SYM_AUTH_TAG_LENGTH lengths so never allocates more than AuthSymMessageHeaderSize
 func UnmarshalAuthSymMessageHeader(data []byte) (*AuthSymMessageHeader, error) {
-	header := &AuthSymMessageHeader{}
-	reader := bytes.NewReader(data)
-	if err := binary.Read(reader, binary.LittleEndian, header.Alg[:]); err != nil {
-		return nil, err
+	if len(data) < authSymMessageHeaderFieldsSize {
+		return nil, ErrCellTruncated
 	}
-	if err := binary.Read(reader, binary.LittleEndian, header.IVLength[:]); err != nil {
-		return nil, err
+	header := &AuthSymMessageHeader{}
+	copy(header.Alg[:], data[0:4])
+	copy(header.IVLength[:], data[4:8])
+	copy(header.AuthTagLength[:], data[8:12])
+	copy(header.MessageLength[:], data[12:16])
+	if binary.LittleEndian.Uint32(header.IVLength[:]) != THEMIS_AUTH_SYM_IV_LENGTH {
+		return nil, ErrCellMalformed
 	}
-	if err := binary.Read(reader, binary.LittleEndian, header.AuthTagLength[:]); err != nil {
-		return nil, err
+	if binary.LittleEndian.Uint32(header.AuthTagLength[:]) != THEMIS_AUTH_SYM_AUTH_TAG_LENGTH {
+		return nil, ErrCellMalformed
 	}
-	if err := binary.Read(reader, binary.LittleEndian, header.MessageLength[:]); err != nil {
-		return nil, err
+	if len(data) < AuthSymMessageHeaderSize {
+		return nil, ErrCellTruncated
 	}
+	header.IV = append(make([]byte, 0, THEMIS_AUTH_SYM_IV_LENGTH), data[authSymMessageHeaderFieldsSize:authSymMessageHeaderFieldsSize+THEMIS_AUTH_SYM_IV_LENGTH]...)
+	header.AuthTag = append(make([]byte, 0, THEMIS_AUTH_SYM_AUTH_TAG_LENGTH), data[authSymMessageHeaderFieldsSize+THEMIS_AUTH_SYM_IV_LENGTH:AuthSymMessageHeaderSize]...)
+	return header, nil
+}
 
-	header.IV = make([]byte, int(binary.LittleEndian.Uint32(header.IVLength[:])))
-	if err := binary.Read(reader, binary.LittleEndian, header.IV[:]); err != nil {
-		return nil, err
+// validateMessageLength compare message length declared in header with real length of encrypted data
+func validateMessageLength(messageLength AuthTagFieldLength, data []byte) error {
+	declared := uint64(binary.LittleEndian.Uint32(messageLength[:]))
+	if uint64(len(data)) < declared {
+		return ErrCellTruncated
 	}
-	header.AuthTag = make([]byte, int(binary.LittleEndian.Uint32(header.AuthTagLength[:])))
-	if err := binary.Read(reader, binary.LittleEndian, header.AuthTag[:]); err != nil {
-		return nil, err
+	if uint64(len(data)) > declared {
+		return ErrCellMalformed
 	}
-	return header, nil
+	return nil
 }
 
 type AuthTag []byte
@@ -163,13 +229,55 @@ func messageToKDFContext(msg []byte) []byte {
 	return output
 }
 
+// messageToLegacyKDFContext return message length encoded as 64-bit value. Themis 0.9.6 and older on 64-bit
+// platforms used size_t for message length in KDF context, so cells encrypted by them use this context
+func messageToLegacyKDFContext(msg []byte) []byte {
+	output := make([]byte, 8)
+	binary.LittleEndian.PutUint64(output, uint64(len(msg)))
+	return output
+}
+
 var ErrInvalidKDFAlgorithm = errors.New("invalid kdf algorithm")
 
-func soterKDF(alg uint32, key, salt []byte) ([]byte, error) {
+// ErrCellBadAlgorithm returned when Secure Cell header has unsupported algorithm, key length or kdf
+var ErrCellBadAlgorithm = ThemisError("unsupported Secure Cell algorithm")
+
+// soterAlgKeyLength return length in bytes of key used by alg
+func soterAlgKeyLength(alg uint32) (int, error) {
+	switch keyLength := alg & SOTER_SYM_KEY_LENGTH_MASK; keyLength {
+	case SOTER_SYM_128_KEY_LENGTH, SOTER_SYM_192_KEY_LENGTH, SOTER_SYM_256_KEY_LENGTH:
+		return int(keyLength / 8), nil
+	}
+	return 0, ErrCellBadAlgorithm
+}
+
+// validateAuthSymAlg check that alg is AES-GCM with supported key length and kdf
+func validateAuthSymAlg(alg uint32) error {
+	if alg&(SOTER_SYM_ALG_MASK|SOTER_SYM_PADDING_MASK) != SOTER_SYM_AES_GCM {
+		return ErrCellBadAlgorithm
+	}
+	if kdf := alg & SOTER_SYM_KDF_MASK; kdf != SOTER_SYM_NOKDF && kdf != SOTER_SYM_PBKDF2 {
+		return ErrCellBadAlgorithm
+	}
+	if alg&^(SOTER_SYM_ALG_MASK|SOTER_SYM_PADDING_MASK|SOTER_SYM_KDF_MASK|SOTER_SYM_KEY_LENGTH_MASK) != 0 {
+		return ErrCellBadAlgorithm
+	}
+	_, err := soterAlgKeyLength(alg)
+	return err
+}
+
+// soterKDF derive prekey from key according to kdf bits of alg. kdfContext used only by SOTER_SYM_PBKDF2
+func soterKDF(alg uint32, key []byte, kdfContext *PBKDF2Context) ([]byte, error) {
 	switch alg & SOTER_SYM_KDF_MASK {
 	case SOTER_SYM_PBKDF2:
-		hmacHash := hmac.New(sha256.New, key)
-		return pbkdf2.Key(key, salt, 0, SOTER_SYM_MAX_KEY_LENGTH/8, func() hash.Hash { return hmacHash }), nil
+		if kdfContext == nil {
+			return nil, ErrInvalidKDFAlgorithm
+		}
+		keyLength, err := soterAlgKeyLength(alg)
+		if err != nil {
+			return nil, err
+		}
+		return pbkdf2.Key(key, kdfContext.Salt, int(kdfContext.IterationCount), keyLength, sha256.New), nil
 	case SOTER_SYM_NOKDF:
 		return key, nil
 	}
@@ -177,36 +285,33 @@ func soterKDF(alg uint32, key, salt []byte) ([]byte, error) {
 
 }
 
-func AuthenticatedSymmetricEncryptMessage(key, message []byte, context Context) (EncryptedData, *AuthSymMessageHeader, error) {
-	kdfKey := themisKDF(key, THEMIS_SYM_KDF_KEY_LABEL, [][]byte{messageToKDFContext(message), []byte(context)})
+// authSymEncrypt encrypt message with AES-GCM using already derived key and random IV
+func authSymEncrypt(kdfKey, message []byte, context Context) (EncryptedData, IV, AuthTag, error) {
 	iv := make([]byte, THEMIS_AUTH_SYM_IV_LENGTH)
 	if n, err := rand.Read(iv); err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	} else if n != THEMIS_AUTH_SYM_IV_LENGTH {
-		return nil, nil, errors.New("can't read enough random data for IV")
+		return nil, nil, nil, errors.New("can't read enough random data for IV")
 	}
 	aes, err := aes.NewCipher(kdfKey)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	aesGCM, err := cipher.NewGCM(aes)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	ciphertext := aesGCM.Seal(nil, iv, message, context)
-	encryptedData := EncryptedData(ciphertext[:len(message)])
-	tag := AuthTag(ciphertext[len(message):])
-	hdr, err := NewAuthSymMessageHeader(uint32(len(message)), IV(iv), tag)
-	if err != nil {
-		return nil, nil, err
-	}
-	return encryptedData, hdr, nil
+	return EncryptedData(ciphertext[:len(message)]), IV(iv), AuthTag(ciphertext[len(message):]), nil
 }
 
-func AuthenticatedSymmetricDecryptMessage(key, encryptedMessage []byte, authTag *AuthSymMessageHeader, context Context) ([]byte, error) {
-	kdfKey := themisKDF(key, THEMIS_SYM_KDF_KEY_LABEL, [][]byte{messageToKDFContext(encryptedMessage), []byte(context)})
-
+// authSymDecrypt decrypt message with AES-GCM using already derived key
+func authSymDecrypt(kdfKey, encryptedMessage []byte, iv IV, authTag AuthTag, context Context) ([]byte, error) {
+	// cipher.AEAD panics on nonce with incorrect length
+	if len(iv) != THEMIS_AUTH_SYM_IV_LENGTH || len(authTag) != THEMIS_AUTH_SYM_AUTH_TAG_LENGTH {
+		return nil, ErrCellMalformed
+	}
 	aes, err := aes.NewCipher(kdfKey)
 	if err != nil {
 		return nil, err
@@ -215,15 +320,86 @@ func AuthenticatedSymmetricDecryptMessage(key, encryptedMessage []byte, authTag
 	if err != nil {
 		return nil, err
 	}
-	authenticatedBlock := make([]byte, 0, len(encryptedMessage)+len(authTag.AuthTag))
+	authenticatedBlock := make([]byte, 0, len(encryptedMessage)+len(authTag))
 	authenticatedBlock = append(authenticatedBlock, encryptedMessage...)
-	authenticatedBlock = append(authenticatedBlock, authTag.AuthTag[:]...)
-	decrypted, err := aesGCM.Open(nil, authTag.IV, authenticatedBlock, []byte(context))
+	authenticatedBlock = append(authenticatedBlock, authTag[:]...)
+	decrypted, err := aesGCM.Open(nil, iv, authenticatedBlock, []byte(context))
 	if err != nil {
-		return nil, err
+		return nil, ErrCellAuthFailed
 	}
 	return decrypted, nil
+}
+
+// authSymKDF derive encryption key with length according to alg from key, message length and context
+func authSymKDF(alg uint32, key []byte, messageLength []byte, context Context) (*SecretBytes, error) {
+	keyLength, err := soterAlgKeyLength(alg)
+	if err != nil {
+		return nil, err
+	}
+	derived := themisKDF(key, THEMIS_SYM_KDF_KEY_LABEL, [][]byte{messageLength, []byte(context)})
+	// wipe whole KDF output, not only part used as key
+	defer Zeroize(derived)
+	kdfKey := NewSecretBytes(keyLength)
+	copy(kdfKey.Bytes(), derived)
+	return kdfKey, nil
+}
 
+func AuthenticatedSymmetricEncryptMessage(key, message []byte, context Context) (EncryptedData, *AuthSymMessageHeader, error) {
+	return authenticatedSymmetricEncryptMessage(THEMIS_AUTH_SYM_ALG, key, message, context)
+}
+
+func authenticatedSymmetricEncryptMessage(alg uint32, key, message []byte, context Context) (EncryptedData, *AuthSymMessageHeader, error) {
+	if err := validateAuthSymAlg(alg); err != nil {
+		return nil, nil, err
+	}
+	if alg&SOTER_SYM_KDF_MASK != SOTER_SYM_NOKDF {
+		return nil, nil, ErrInvalidKDFAlgorithm
+	}
+	kdfKey, err := authSymKDF(alg, key, messageToKDFContext(message), context)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer kdfKey.Destroy()
+	encryptedData, iv, tag, err := authSymEncrypt(kdfKey.Bytes(), message, context)
+	if err != nil {
+		return nil, nil, err
+	}
+	hdr, err := NewAuthSymMessageHeader(uint32(len(message)), iv, tag)
+	if err != nil {
+		return nil, nil, err
+	}
+	binary.LittleEndian.PutUint32(hdr.Alg[:], alg)
+	return encryptedData, hdr, nil
+}
+
+// AuthenticatedSymmetricDecryptMessage decrypt message with key length according to algorithm from header
+func AuthenticatedSymmetricDecryptMessage(key, encryptedMessage []byte, authTag *AuthSymMessageHeader, context Context) ([]byte, error) {
+	alg := binary.LittleEndian.Uint32(authTag.Alg[:])
+	if err := validateAuthSymAlg(alg); err != nil {
+		return nil, err
+	}
+	if alg&SOTER_SYM_KDF_MASK != SOTER_SYM_NOKDF {
+		return nil, ErrCellBadAlgorithm
+	}
+	kdfKey, err := authSymKDF(alg, key, messageToKDFContext(encryptedMessage), context)
+	if err != nil {
+		return nil, err
+	}
+	decrypted, err := authSymDecrypt(kdfKey.Bytes(), encryptedMessage, authTag.IV, authTag.AuthTag, context)
+	kdfKey.Destroy()
+	if err != ErrCellAuthFailed {
+		return decrypted, err
+	}
+	// same fallback as Themis does for data encrypted by old versions with another KDF context
+	kdfKey, err = authSymKDF(alg, key, messageToLegacyKDFContext(encryptedMessage), context)
+	if err != nil {
+		return nil, err
+	}
+	defer kdfKey.Destroy()
+	if decrypted, err := authSymDecrypt(kdfKey.Bytes(), encryptedMessage, authTag.IV, authTag.AuthTag, context); err == nil {
+		return decrypted, nil
+	}
+	return nil, ErrCellAuthFailed
 }
 
 type AuthenticationContext []byte
@@ -240,14 +416,109 @@ func CellSealEncrypt(key, data []byte, context Context) (EncryptedData, error) {
 	return append(authContext, encryptedData...), nil
 }
 
+// CellSealEncryptWithKeyLength encrypt data in Seal mode with AES-GCM using key length one of SOTER_SYM_128_KEY_LENGTH,
+// SOTER_SYM_192_KEY_LENGTH or SOTER_SYM_256_KEY_LENGTH
+func CellSealEncryptWithKeyLength(key, data []byte, context Context, keyLength uint32) (EncryptedData, error) {
+	if keyLength&^SOTER_SYM_KEY_LENGTH_MASK != 0 {
+		return nil, ErrCellBadAlgorithm
+	}
+	encryptedData, authHeader, err := authenticatedSymmetricEncryptMessage(SOTER_SYM_AES_GCM|keyLength, key, data, context)
+	if err != nil {
+		return nil, err
+	}
+	authContext, err := authHeader.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	return append(authContext, encryptedData...), nil
+}
+
 func CellSealDecrypt(key, data []byte, context Context) (EncryptedData, error) {
-	authHeader, err := UnmarshalAuthSymMessageHeader(data[:AuthSymMessageHeaderSize])
+	authHeader, err := UnmarshalAuthSymMessageHeader(data)
 	if err != nil {
 		return nil, err
 	}
+	if err := validateMessageLength(authHeader.MessageLength, data[AuthSymMessageHeaderSize:]); err != nil {
+		return nil, err
+	}
 	decryptedData, err := AuthenticatedSymmetricDecryptMessage(key, data[AuthSymMessageHeaderSize:], authHeader, context)
 	if err != nil {
 		return nil, err
 	}
 	return decryptedData, nil
 }
+
+// CellTokenProtectEncrypt encrypt data in Token Protect mode. Returns encrypted data with same length as source data and
+// authentication token that stores AuthSymMessageHeader and should be passed to CellTokenProtectDecrypt
+func CellTokenProtectEncrypt(key, data []byte, context Context) (EncryptedData, []byte, error) {
+	encryptedData, authHeader, err := AuthenticatedSymmetricEncryptMessage(key, data, context)
+	if err != nil {
+		return nil, nil, err
+	}
+	token, err := authHeader.Marshal()
+	if err != nil {
+		return nil, nil, err
+	}
+	return encryptedData, token, nil
+}
+
+var ErrInvalidAuthToken = errors.New("incorrect authentication token")
+
+// CellTokenProtectDecrypt decrypt data encrypted in Token Protect mode with authentication token
+func CellTokenProtectDecrypt(key, data, token []byte, context Context) ([]byte, error) {
+	if len(token) != AuthSymMessageHeaderSize {
+		return nil, ErrInvalidAuthToken
+	}
+	authHeader, err := UnmarshalAuthSymMessageHeader(token)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateMessageLength(authHeader.MessageLength, data); err != nil {
+		return nil, err
+	}
+	return AuthenticatedSymmetricDecryptMessage(key, data, authHeader, context)
+}
+
+// ErrMissingContext returned when Context Imprint mode used without context
+var ErrMissingContext = ThemisError("associated context is required in Context Imprint mode")
+
+// symmetricEncryptMessage encrypt message with AES-CTR using key and iv derived from key and context. Same function
+// used for decryption
+func symmetricEncryptMessage(key, message []byte, context Context) ([]byte, error) {
+	return symmetricEncryptMessageWithKDFContext(key, message, context, messageToKDFContext(message))
+}
+
+func symmetricEncryptMessageWithKDFContext(key, message []byte, context Context, messageLength []byte) ([]byte, error) {
+	if len(context) == 0 {
+		return nil, ErrMissingContext
+	}
+	kdfKey := NewSecretBytesFromBytes(themisKDF(key, THEMIS_SYM_KDF_KEY_LABEL, [][]byte{messageLength, []byte(context)}))
+	defer kdfKey.Destroy()
+	iv := themisKDF(kdfKey.Bytes(), THEMIS_SYM_KDF_IV_LABEL, [][]byte{[]byte(context)})[:THEMIS_SYM_IV_LENGTH]
+	aes, err := aes.NewCipher(kdfKey.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	output := make([]byte, len(message))
+	cipher.NewCTR(aes, iv).XORKeyStream(output, message)
+	return output, nil
+}
+
+// CellContextImprintEncrypt encrypt data in Context Imprint mode. Output has same length as data and is deterministic:
+// same key, data and context always produce same encrypted data. Context is required
+func CellContextImprintEncrypt(key, data []byte, context Context) (EncryptedData, error) {
+	return symmetricEncryptMessage(key, data, context)
+}
+
+// CellContextImprintDecrypt decrypt data encrypted in Context Imprint mode. This mode has no authentication so
+// incorrect key or context produce garbage instead of error. Due to this there is no fallback to legacy KDF like
+// in Seal and Token Protect modes, use CellContextImprintDecryptLegacy for data encrypted by Themis 0.9.6 and older
+func CellContextImprintDecrypt(key, data []byte, context Context) ([]byte, error) {
+	return symmetricEncryptMessage(key, data, context)
+}
+
+// CellContextImprintDecryptLegacy decrypt data encrypted in Context Imprint mode by Themis 0.9.6 and older on 64-bit
+// platforms that used 64-bit message length in KDF context
+func CellContextImprintDecryptLegacy(key, data []byte, context Context) ([]byte, error) {
+	return symmetricEncryptMessageWithKDFContext(key, data, context, messageToLegacyKDFContext(data))
+}
@@ -0,0 +1,222 @@
+package gothemis
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	THEMIS_AUTH_SYM_PASSPHRASE_ALG uint32 = SOTER_SYM_AES_GCM | SOTER_SYM_PBKDF2 | THEMIS_AUTH_SYM_KEY_LENGTH
+
+	// THEMIS_AUTH_SYM_PBKDF2_ITERATIONS default iteration count used by Themis for passphrases
+	THEMIS_AUTH_SYM_PBKDF2_ITERATIONS  = 200000
+	THEMIS_AUTH_SYM_PBKDF2_SALT_LENGTH = 16
+
+	// MaxPBKDF2Iterations limits iteration count read from encrypted data. Count stored in data, so without limit
+	// anyone who can change encrypted data or key file can make decryption run PBKDF2 up to 2^32-1 times. Limit is
+	// 10 times more than Themis default to accept data encrypted with stronger settings
+	MaxPBKDF2Iterations = 10 * THEMIS_AUTH_SYM_PBKDF2_ITERATIONS
+)
+
+// pbkdf2ContextStaticSize iteration_count[uint32] + salt_length[uint16]
+const pbkdf2ContextStaticSize = 4 + 2
+
+// passphraseMessageHeaderFieldsSize alg, iv_length, auth_tag_length, message_length, kdf_context_length as uint32
+const passphraseMessageHeaderFieldsSize = authSymMessageHeaderFieldsSize + 4
+
+// PBKDF2Context stores parameters of PBKDF2 used to derive prekey from passphrase
+type PBKDF2Context struct {
+	IterationCount uint32
+	Salt           []byte
+}
+
+var ErrInvalidPBKDF2Context = errors.New("incorrect PBKDF2 context")
+
+func newPBKDF2Context(iterations int) (*PBKDF2Context, error) {
+	if iterations <= 0 || iterations > MaxPBKDF2Iterations {
+		return nil, ErrInvalidPBKDF2Context
+	}
+	salt := make([]byte, THEMIS_AUTH_SYM_PBKDF2_SALT_LENGTH)
+	if n, err := rand.Read(salt); err != nil {
+		return nil, err
+	} else if n != THEMIS_AUTH_SYM_PBKDF2_SALT_LENGTH {
+		return nil, errors.New("can't read enough random data for salt")
+	}
+	return &PBKDF2Context{IterationCount: uint32(iterations), Salt: salt}, nil
+}
+
+// Marshal return PBKDF2Context in Themis format: iteration count, salt length and salt in little endian
+func (ctx *PBKDF2Context) Marshal() []byte {
+	output := make([]byte, pbkdf2ContextStaticSize+len(ctx.Salt))
+	binary.LittleEndian.PutUint32(output[:4], ctx.IterationCount)
+	binary.LittleEndian.PutUint16(output[4:6], uint16(len(ctx.Salt)))
+	copy(output[pbkdf2ContextStaticSize:], ctx.Salt)
+	return output
+}
+
+// UnmarshalPBKDF2Context parse PBKDF2Context from data and check that data has no trailing bytes. Returns
+// ErrInvalidPBKDF2Context if iteration count is 0 or more than MaxPBKDF2Iterations
+func UnmarshalPBKDF2Context(data []byte) (*PBKDF2Context, error) {
+	if len(data) < pbkdf2ContextStaticSize {
+		return nil, ErrInvalidPBKDF2Context
+	}
+	ctx := &PBKDF2Context{IterationCount: binary.LittleEndian.Uint32(data[:4])}
+	saltLength := int(binary.LittleEndian.Uint16(data[4:6]))
+	if ctx.IterationCount == 0 || ctx.IterationCount > MaxPBKDF2Iterations || len(data) != pbkdf2ContextStaticSize+saltLength {
+		return nil, ErrInvalidPBKDF2Context
+	}
+	ctx.Salt = data[pbkdf2ContextStaticSize:]
+	return ctx, nil
+}
+
+// AuthSymPassphraseMessageHeader header of Secure Cell Seal encrypted with passphrase. It extends AuthSymMessageHeader
+// with kdf context stored after auth tag
+type AuthSymPassphraseMessageHeader struct {
+	AuthSymMessageHeader
+	KDFContextLength AuthTagFieldLength
+	KDFContext       []byte
+}
+
+// Size return length of marshaled header
+func (hdr *AuthSymPassphraseMessageHeader) Size() int {
+	return passphraseMessageHeaderFieldsSize + len(hdr.IV) + len(hdr.AuthTag) + len(hdr.KDFContext)
+}
+
+// Marshal return header in Themis format
+func (hdr *AuthSymPassphraseMessageHeader) Marshal() ([]byte, error) {
+	output := make([]byte, 0, hdr.Size())
+	output = append(output, hdr.Alg[:]...)
+	output = append(output, hdr.IVLength[:]...)
+	output = append(output, hdr.AuthTagLength[:]...)
+	output = append(output, hdr.MessageLength[:]...)
+	output = append(output, hdr.KDFContextLength[:]...)
+	output = append(output, hdr.IV...)
+	output = append(output, hdr.AuthTag...)
+	output = append(output, hdr.KDFContext...)
+	return output, nil
+}
+
+// UnmarshalAuthSymPassphraseMessageHeader parse header from data. IV, auth tag and kdf context reference data
+func UnmarshalAuthSymPassphraseMessageHeader(data []byte) (*AuthSymPassphraseMessageHeader, error) {
+	if len(data) < passphraseMessageHeaderFieldsSize {
+		return nil, ErrCellTruncated
+	}
+	hdr := &AuthSymPassphraseMessageHeader{}
+	copy(hdr.Alg[:], data[0:4])
+	copy(hdr.IVLength[:], data[4:8])
+	copy(hdr.AuthTagLength[:], data[8:12])
+	copy(hdr.MessageLength[:], data[12:16])
+	copy(hdr.KDFContextLength[:], data[16:20])
+	if binary.LittleEndian.Uint32(hdr.IVLength[:]) != THEMIS_AUTH_SYM_IV_LENGTH {
+		return nil, ErrCellMalformed
+	}
+	if binary.LittleEndian.Uint32(hdr.AuthTagLength[:]) != THEMIS_AUTH_SYM_AUTH_TAG_LENGTH {
+		return nil, ErrCellMalformed
+	}
+	kdfContextLength := uint64(binary.LittleEndian.Uint32(hdr.KDFContextLength[:]))
+	rest := data[passphraseMessageHeaderFieldsSize:]
+	if uint64(len(rest)) < THEMIS_AUTH_SYM_IV_LENGTH+THEMIS_AUTH_SYM_AUTH_TAG_LENGTH+kdfContextLength {
+		return nil, ErrCellTruncated
+	}
+	hdr.IV = IV(rest[:THEMIS_AUTH_SYM_IV_LENGTH])
+	rest = rest[THEMIS_AUTH_SYM_IV_LENGTH:]
+	hdr.AuthTag = AuthTag(rest[:THEMIS_AUTH_SYM_AUTH_TAG_LENGTH])
+	rest = rest[THEMIS_AUTH_SYM_AUTH_TAG_LENGTH:]
+	hdr.KDFContext = rest[:kdfContextLength]
+	return hdr, nil
+}
+
+// ErrMissingPassphrase returned when passphrase is empty
+var ErrMissingPassphrase = ThemisError("empty passphrase for Secure Cell")
+
+// passphraseKDF derive encryption key from passphrase using PBKDF2 and Themis KDF
+func passphraseKDF(alg uint32, passphrase []byte, kdfContext *PBKDF2Context, messageLength int, context Context) (*SecretBytes, error) {
+	derived, err := soterKDF(alg, passphrase, kdfContext)
+	if err != nil {
+		return nil, err
+	}
+	prekey := NewSecretBytesFromBytes(derived)
+	defer prekey.Destroy()
+	lengthContext := make([]byte, 4)
+	binary.LittleEndian.PutUint32(lengthContext, uint32(messageLength))
+	return authSymKDF(alg, prekey.Bytes(), lengthContext, context)
+}
+
+func cellSealEncryptWithPassphrase(passphrase []byte, data []byte, context Context, iterations int) (EncryptedData, error) {
+	if len(passphrase) == 0 {
+		return nil, ErrMissingPassphrase
+	}
+	kdfContext, err := newPBKDF2Context(iterations)
+	if err != nil {
+		return nil, err
+	}
+	kdfKey, err := passphraseKDF(THEMIS_AUTH_SYM_PASSPHRASE_ALG, passphrase, kdfContext, len(data), context)
+	if err != nil {
+		return nil, err
+	}
+	encryptedData, iv, tag, err := authSymEncrypt(kdfKey.Bytes(), data, context)
+	kdfKey.Destroy()
+	if err != nil {
+		return nil, err
+	}
+	hdr := &AuthSymPassphraseMessageHeader{KDFContext: kdfContext.Marshal()}
+	binary.LittleEndian.PutUint32(hdr.Alg[:], THEMIS_AUTH_SYM_PASSPHRASE_ALG)
+	binary.LittleEndian.PutUint32(hdr.IVLength[:], uint32(len(iv)))
+	binary.LittleEndian.PutUint32(hdr.AuthTagLength[:], uint32(len(tag)))
+	binary.LittleEndian.PutUint32(hdr.MessageLength[:], uint32(len(data)))
+	binary.LittleEndian.PutUint32(hdr.KDFContextLength[:], uint32(len(hdr.KDFContext)))
+	hdr.IV = iv
+	hdr.AuthTag = tag
+	output, err := hdr.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	return append(output, encryptedData...), nil
+}
+
+// CellSealEncryptWithPassphrase encrypt data in Seal mode with key derived from passphrase by PBKDF2 with
+// THEMIS_AUTH_SYM_PBKDF2_ITERATIONS iterations. Output compatible with Themis SealWithPassphrase
+func CellSealEncryptWithPassphrase(passphrase string, data []byte, context Context) (EncryptedData, error) {
+	passphraseBytes := NewSecretBytesFromBytes([]byte(passphrase))
+	defer passphraseBytes.Destroy()
+	return cellSealEncryptWithPassphrase(passphraseBytes.Bytes(), data, context, THEMIS_AUTH_SYM_PBKDF2_ITERATIONS)
+}
+
+// CellSealDecryptWithPassphrase decrypt data encrypted in Seal mode with passphrase
+func CellSealDecryptWithPassphrase(passphrase string, data []byte, context Context) ([]byte, error) {
+	passphraseBytes := NewSecretBytesFromBytes([]byte(passphrase))
+	defer passphraseBytes.Destroy()
+	return cellSealDecryptWithPassphrase(passphraseBytes.Bytes(), data, context)
+}
+
+func cellSealDecryptWithPassphrase(passphrase []byte, data []byte, context Context) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, ErrMissingPassphrase
+	}
+	hdr, err := UnmarshalAuthSymPassphraseMessageHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	alg := binary.LittleEndian.Uint32(hdr.Alg[:])
+	if err := validateAuthSymAlg(alg); err != nil {
+		return nil, err
+	}
+	if alg&SOTER_SYM_KDF_MASK != SOTER_SYM_PBKDF2 {
+		return nil, ErrCellBadAlgorithm
+	}
+	encryptedData := data[hdr.Size():]
+	if err := validateMessageLength(hdr.MessageLength, encryptedData); err != nil {
+		return nil, err
+	}
+	kdfContext, err := UnmarshalPBKDF2Context(hdr.KDFContext)
+	if err != nil {
+		return nil, err
+	}
+	kdfKey, err := passphraseKDF(alg, passphrase, kdfContext, len(encryptedData), context)
+	if err != nil {
+		return nil, err
+	}
+	defer kdfKey.Destroy()
+	return authSymDecrypt(kdfKey.Bytes(), encryptedData, hdr.IV, hdr.AuthTag, context)
+}
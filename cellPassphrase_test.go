@@ -0,0 +1,129 @@
+package gothemis
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/cossacklabs/themis/gothemis/cell"
+)
+
+func TestCellSealWithPassphrase(t *testing.T) {
+	passphrase := "secret passphrase"
+	message := make([]byte, 100)
+	context := make([]byte, 100)
+	themisCell, err := cell.SealWithPassphrase(passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		rand.Read(message)
+		rand.Read(context)
+
+		encrypted, err := CellSealEncryptWithPassphrase(passphrase, message, context)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if alg := binary.LittleEndian.Uint32(encrypted[:4]); alg != THEMIS_AUTH_SYM_PASSPHRASE_ALG {
+			t.Fatalf("incorrect alg %x", alg)
+		}
+		data, err := themisCell.Decrypt(encrypted, context)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, message) {
+			t.Fatal("data not equal")
+		}
+
+		encrypted, err = themisCell.Encrypt(message, context)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err = CellSealDecryptWithPassphrase(passphrase, encrypted, context)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, message) {
+			t.Fatal("data not equal")
+		}
+	}
+}
+
+func TestCellSealWithPassphraseRoundTrip(t *testing.T) {
+	passphrase := "secret passphrase"
+	message := []byte("some message")
+	context := []byte("some context")
+	encrypted, err := cellSealEncryptWithPassphrase([]byte(passphrase), message, context, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := CellSealDecryptWithPassphrase(passphrase, encrypted, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, message) {
+		t.Fatal("data not equal")
+	}
+	if _, err := CellSealDecryptWithPassphrase("incorrect passphrase", encrypted, context); err == nil {
+		t.Fatal("expected error with incorrect passphrase")
+	}
+	if _, err := CellSealDecryptWithPassphrase(passphrase, encrypted, nil); err == nil {
+		t.Fatal("expected error with incorrect context")
+	}
+	for i := 0; i < len(encrypted); i++ {
+		if _, err := CellSealDecryptWithPassphrase(passphrase, encrypted[:i], context); err == nil {
+			t.Fatalf("expected error on truncated data with length %d", i)
+		}
+	}
+	if _, err := CellSealEncryptWithPassphrase("", message, context); err != ErrMissingPassphrase {
+		t.Fatal("expected ErrMissingPassphrase")
+	}
+}
+
+func TestPBKDF2Context(t *testing.T) {
+	ctx, err := newPBKDF2Context(THEMIS_AUTH_SYM_PBKDF2_ITERATIONS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := UnmarshalPBKDF2Context(ctx.Marshal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.IterationCount != THEMIS_AUTH_SYM_PBKDF2_ITERATIONS || !bytes.Equal(parsed.Salt, ctx.Salt) {
+		t.Fatal("parsed context not equal")
+	}
+	if _, err := UnmarshalPBKDF2Context(ctx.Marshal()[:pbkdf2ContextStaticSize+1]); err != ErrInvalidPBKDF2Context {
+		t.Fatal("expected ErrInvalidPBKDF2Context")
+	}
+	for _, iterations := range []uint32{0, MaxPBKDF2Iterations + 1, math.MaxUint32} {
+		ctx.IterationCount = iterations
+		if _, err := UnmarshalPBKDF2Context(ctx.Marshal()); err != ErrInvalidPBKDF2Context {
+			t.Fatal("expected ErrInvalidPBKDF2Context", iterations)
+		}
+	}
+	ctx.IterationCount = MaxPBKDF2Iterations
+	if _, err := UnmarshalPBKDF2Context(ctx.Marshal()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newPBKDF2Context(MaxPBKDF2Iterations + 1); err != ErrInvalidPBKDF2Context {
+		t.Fatal("expected ErrInvalidPBKDF2Context", err)
+	}
+}
+
+func TestCellSealPassphraseIterationLimit(t *testing.T) {
+	encrypted, err := cellSealEncryptWithPassphrase([]byte("passphrase"), []byte("some data"), nil, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// iteration count is first field of KDF context stored after IV and auth tag
+	offset := passphraseMessageHeaderFieldsSize + THEMIS_AUTH_SYM_IV_LENGTH + THEMIS_AUTH_SYM_AUTH_TAG_LENGTH
+	if binary.LittleEndian.Uint32(encrypted[offset:]) != 10 {
+		t.Fatal("incorrect offset of iteration count")
+	}
+	binary.LittleEndian.PutUint32(encrypted[offset:], math.MaxUint32)
+	if _, err := CellSealDecryptWithPassphrase("passphrase", encrypted, nil); err != ErrInvalidPBKDF2Context {
+		t.Fatal("expected ErrInvalidPBKDF2Context", err)
+	}
+}
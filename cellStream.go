@@ -0,0 +1,315 @@
+package gothemis
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// Stream of Secure Cells has next structure:
+// {
+//	magic      [4]byte
+//	chunkSize  uint32
+//	streamID   [cellStreamIDLength]byte
+// }
+// followed by chunks:
+// {
+//	flags  byte
+//	length uint32
+//	cell   [length]byte
+// }
+// Each chunk is Secure Cell in Seal mode which context is user's context + stream header + chunk index + flags. So
+// chunks can't be reordered, moved between streams or marked as final without detection. Every chunk except last
+// stores exactly chunkSize bytes and stream must end with chunk marked as final.
+
+var cellStreamMagic = []byte("TSC1")
+
+const (
+	cellStreamIDLength     = 16
+	cellStreamHeaderSize   = 4 + 4 + cellStreamIDLength
+	cellStreamChunkHdrSize = 1 + 4
+
+	cellStreamChunkFinal byte = 1
+
+	// DefaultCellStreamChunkSize size of plaintext stored in one chunk
+	DefaultCellStreamChunkSize = 64 * 1024
+	// MaxCellStreamChunkSize limits memory used by reader for one chunk
+	MaxCellStreamChunkSize = 16 * 1024 * 1024
+)
+
+// Errors returned by CellSealReader and CellSealWriter
+var (
+	ErrCellStreamInvalidChunkSize = ThemisError("incorrect chunk size of Secure Cell stream")
+	ErrCellStreamCorrupted        = ThemisError("Secure Cell stream corrupted")
+	ErrCellStreamTruncated        = ThemisError("Secure Cell stream truncated")
+	ErrCellStreamTrailingData     = ThemisError("Secure Cell stream has data after final chunk")
+	ErrCellStreamClosed           = ThemisError("Secure Cell stream closed")
+)
+
+// chunkContext return context used to encrypt chunk with index
+func chunkContext(context Context, header []byte, index uint64, flags byte) []byte {
+	output := make([]byte, 0, len(context)+len(header)+8+1)
+	output = append(output, context...)
+	output = append(output, header...)
+	var indexBytes [8]byte
+	binary.LittleEndian.PutUint64(indexBytes[:], index)
+	output = append(output, indexBytes[:]...)
+	return append(output, flags)
+}
+
+// CellSealWriter encrypts data written to it by chunks and writes them to underlying writer. Close must be called
+// to write final chunk
+type CellSealWriter struct {
+	key     *SecretBytes
+	context Context
+	writer  io.Writer
+	header  []byte
+	buffer  []byte
+	index   uint64
+	started bool
+	closed  bool
+	err     error
+}
+
+// NewCellSealWriter return CellSealWriter with DefaultCellStreamChunkSize
+func NewCellSealWriter(key []byte, context Context, writer io.Writer) (*CellSealWriter, error) {
+	return NewCellSealWriterWithChunkSize(key, context, writer, DefaultCellStreamChunkSize)
+}
+
+// NewCellSealWriterWithChunkSize return CellSealWriter that stores chunkSize bytes of data in each chunk
+func NewCellSealWriterWithChunkSize(key []byte, context Context, writer io.Writer, chunkSize int) (*CellSealWriter, error) {
+	if len(key) == 0 {
+		return nil, ErrMissingKey
+	}
+	if chunkSize <= 0 || chunkSize > MaxCellStreamChunkSize {
+		return nil, ErrCellStreamInvalidChunkSize
+	}
+	header := make([]byte, cellStreamHeaderSize)
+	copy(header[:4], cellStreamMagic)
+	binary.LittleEndian.PutUint32(header[4:8], uint32(chunkSize))
+	if n, err := rand.Read(header[8:]); err != nil {
+		return nil, err
+	} else if n != cellStreamIDLength {
+		return nil, errors.New("can't read enough random data for stream id")
+	}
+	return &CellSealWriter{
+		key:     copyKey(key),
+		context: context,
+		writer:  writer,
+		header:  header,
+		buffer:  make([]byte, 0, chunkSize),
+	}, nil
+}
+
+func (w *CellSealWriter) writeChunk(flags byte) error {
+	if !w.started {
+		if _, err := w.writer.Write(w.header); err != nil {
+			return err
+		}
+		w.started = true
+	}
+	encrypted, err := CellSealEncrypt(w.key.Bytes(), w.buffer, chunkContext(w.context, w.header, w.index, flags))
+	if err != nil {
+		return err
+	}
+	var chunkHeader [cellStreamChunkHdrSize]byte
+	chunkHeader[0] = flags
+	binary.LittleEndian.PutUint32(chunkHeader[1:], uint32(len(encrypted)))
+	if _, err := w.writer.Write(chunkHeader[:]); err != nil {
+		return err
+	}
+	if _, err := w.writer.Write(encrypted); err != nil {
+		return err
+	}
+	Zeroize(w.buffer)
+	w.buffer = w.buffer[:0]
+	w.index++
+	return nil
+}
+
+// Write encrypts data by chunks. Full chunk is written only when next data arrives to know which chunk is final
+func (w *CellSealWriter) Write(data []byte) (int, error) {
+	if w.closed {
+		return 0, ErrCellStreamClosed
+	}
+	if w.err != nil {
+		return 0, w.err
+	}
+	written := 0
+	for len(data) > 0 {
+		if len(w.buffer) == cap(w.buffer) {
+			if err := w.writeChunk(0); err != nil {
+				w.err = err
+				return written, err
+			}
+		}
+		n := min(cap(w.buffer)-len(w.buffer), len(data))
+		w.buffer = append(w.buffer, data[:n]...)
+		data = data[n:]
+		written += n
+	}
+	return written, nil
+}
+
+// Close writes buffered data as final chunk and wipes key, even if previous Write failed. It doesn't close
+// underlying writer
+func (w *CellSealWriter) Close() error {
+	if w.closed {
+		return ErrCellStreamClosed
+	}
+	defer w.key.Destroy()
+	w.closed = true
+	if w.err != nil {
+		return w.err
+	}
+	return w.writeChunk(cellStreamChunkFinal)
+}
+
+// CellSealReader decrypts stream written by CellSealWriter
+type CellSealReader struct {
+	key       *SecretBytes
+	context   Context
+	reader    io.Reader
+	header    []byte
+	chunkSize int
+	chunk     []byte
+	decrypted []byte
+	index     uint64
+	final     bool
+	checkEOF  bool
+	err       error
+}
+
+// NewCellSealReader return CellSealReader that reads encrypted stream from reader. Reader stops reading right after
+// final chunk and doesn't check what follows it, so stream may be embedded in other data and reading doesn't block
+// on connection that stays open after stream. Use NewCellSealReaderWithEOFCheck to reject data after final chunk
+func NewCellSealReader(key []byte, context Context, reader io.Reader) (*CellSealReader, error) {
+	if len(key) == 0 {
+		return nil, ErrMissingKey
+	}
+	return &CellSealReader{key: copyKey(key), context: context, reader: reader}, nil
+}
+
+// NewCellSealReaderWithEOFCheck return CellSealReader that after final chunk reads one more byte from reader and
+// returns ErrCellStreamTrailingData instead of plaintext of final chunk if reader doesn't end with io.EOF. Read of
+// final chunk blocks until reader ends
+func NewCellSealReaderWithEOFCheck(key []byte, context Context, reader io.Reader) (*CellSealReader, error) {
+	cellReader, err := NewCellSealReader(key, context, reader)
+	if err != nil {
+		return nil, err
+	}
+	cellReader.checkEOF = true
+	return cellReader, nil
+}
+
+// readFull works like io.ReadFull but returns ErrCellStreamTruncated if stream ends before buf filled
+func (r *CellSealReader) readFull(buf []byte) error {
+	if _, err := io.ReadFull(r.reader, buf); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return ErrCellStreamTruncated
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *CellSealReader) readHeader() error {
+	header := make([]byte, cellStreamHeaderSize)
+	if err := r.readFull(header); err != nil {
+		return err
+	}
+	if string(header[:4]) != string(cellStreamMagic) {
+		return ErrCellStreamCorrupted
+	}
+	chunkSize := binary.LittleEndian.Uint32(header[4:8])
+	if chunkSize == 0 || chunkSize > MaxCellStreamChunkSize {
+		return ErrCellStreamInvalidChunkSize
+	}
+	r.header = header
+	r.chunkSize = int(chunkSize)
+	r.chunk = make([]byte, 0, r.chunkSize+AuthSymMessageHeaderSize)
+	return nil
+}
+
+func (r *CellSealReader) readChunk() error {
+	if r.header == nil {
+		if err := r.readHeader(); err != nil {
+			return err
+		}
+	}
+	var chunkHeader [cellStreamChunkHdrSize]byte
+	if err := r.readFull(chunkHeader[:]); err != nil {
+		return err
+	}
+	flags := chunkHeader[0]
+	length := int64(binary.LittleEndian.Uint32(chunkHeader[1:]))
+	switch flags {
+	case 0:
+		if length != int64(r.chunkSize+AuthSymMessageHeaderSize) {
+			return ErrCellStreamCorrupted
+		}
+	case cellStreamChunkFinal:
+		if length < AuthSymMessageHeaderSize || length > int64(r.chunkSize+AuthSymMessageHeaderSize) {
+			return ErrCellStreamCorrupted
+		}
+	default:
+		return ErrCellStreamCorrupted
+	}
+	r.chunk = r.chunk[:length]
+	if err := r.readFull(r.chunk); err != nil {
+		return err
+	}
+	decrypted, err := CellSealDecrypt(r.key.Bytes(), r.chunk, chunkContext(r.context, r.header, r.index, flags))
+	if err != nil {
+		return err
+	}
+	if flags == cellStreamChunkFinal {
+		r.final = true
+		// key not needed after final chunk
+		r.key.Destroy()
+		if err := r.checkEndOfStream(); err != nil {
+			Zeroize(decrypted)
+			return err
+		}
+	}
+	r.decrypted = decrypted
+	r.index++
+	return nil
+}
+
+// checkEndOfStream returns ErrCellStreamTrailingData if checkEOF set and reader has data after final chunk
+func (r *CellSealReader) checkEndOfStream() error {
+	if !r.checkEOF {
+		return nil
+	}
+	var extra [1]byte
+	if _, err := io.ReadFull(r.reader, extra[:]); err != io.EOF {
+		if err == nil {
+			return ErrCellStreamTrailingData
+		}
+		return err
+	}
+	return nil
+}
+
+// Read decrypted data. Returns io.EOF only after final chunk authenticated
+func (r *CellSealReader) Read(data []byte) (int, error) {
+	for len(r.decrypted) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.final {
+			return 0, io.EOF
+		}
+		if err := r.readChunk(); err != nil {
+			r.err = err
+			r.key.Destroy()
+			return 0, err
+		}
+	}
+	n := copy(data, r.decrypted)
+	Zeroize(r.decrypted[:n])
+	r.decrypted = r.decrypted[n:]
+	return n, nil
+}
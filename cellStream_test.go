@@ -0,0 +1,198 @@
+package gothemis
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+const testStreamChunkSize = 16
+
+func encryptStream(key, data []byte, context Context, t *testing.T) []byte {
+	output := &bytes.Buffer{}
+	writer, err := NewCellSealWriterWithChunkSize(key, context, output, testStreamChunkSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// write by small parts to check buffering
+	for i := 0; i < len(data); i += 5 {
+		if _, err := writer.Write(data[i:min(i+5, len(data))]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return output.Bytes()
+}
+
+func decryptStream(key, data []byte, context Context) ([]byte, error) {
+	reader, err := NewCellSealReader(key, context, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(reader)
+}
+
+// streamChunks split encrypted stream on header and chunks
+func streamChunks(data []byte) ([]byte, [][]byte) {
+	chunkLength := cellStreamChunkHdrSize + testStreamChunkSize + AuthSymMessageHeaderSize
+	header := data[:cellStreamHeaderSize]
+	var chunks [][]byte
+	for data = data[cellStreamHeaderSize:]; len(data) > 0; data = data[min(chunkLength, len(data)):] {
+		chunks = append(chunks, data[:min(chunkLength, len(data))])
+	}
+	return header, chunks
+}
+
+func joinStream(header []byte, chunks ...[]byte) []byte {
+	output := append([]byte{}, header...)
+	for _, chunk := range chunks {
+		output = append(output, chunk...)
+	}
+	return output
+}
+
+func TestCellSealStream(t *testing.T) {
+	key := []byte("some key")
+	context := []byte("some context")
+	for _, size := range []int{0, 1, testStreamChunkSize - 1, testStreamChunkSize, testStreamChunkSize + 1, testStreamChunkSize * 3, 1000} {
+		data := make([]byte, size)
+		rand.Read(data)
+		encrypted := encryptStream(key, data, context, t)
+		decrypted, err := decryptStream(key, encrypted, context)
+		if err != nil {
+			t.Fatal(size, err)
+		}
+		if !bytes.Equal(decrypted, data) {
+			t.Fatal("decrypted data not equal to source data")
+		}
+		if _, err := decryptStream([]byte("incorrect key"), encrypted, context); err == nil {
+			t.Fatal("expected error with incorrect key")
+		}
+		if _, err := decryptStream(key, encrypted, nil); err == nil {
+			t.Fatal("expected error with incorrect context")
+		}
+	}
+}
+
+func TestCellSealStreamTampering(t *testing.T) {
+	key := []byte("some key")
+	data := make([]byte, testStreamChunkSize*3+1)
+	rand.Read(data)
+	encrypted := encryptStream(key, data, nil, t)
+	header, chunks := streamChunks(encrypted)
+	if len(chunks) != 4 {
+		t.Fatalf("unexpected count of chunks %d", len(chunks))
+	}
+
+	// truncation on chunk border
+	if _, err := decryptStream(key, joinStream(header, chunks[:3]...), nil); err != ErrCellStreamTruncated {
+		t.Fatal("expected ErrCellStreamTruncated", err)
+	}
+	// truncation inside chunk
+	if _, err := decryptStream(key, encrypted[:len(encrypted)-1], nil); err != ErrCellStreamTruncated {
+		t.Fatal("expected ErrCellStreamTruncated", err)
+	}
+	// reordering
+	if _, err := decryptStream(key, joinStream(header, chunks[1], chunks[0], chunks[2], chunks[3]), nil); err == nil {
+		t.Fatal("expected error on reordered chunks")
+	}
+	// non-final chunk marked as final
+	fakeFinal := append([]byte{}, chunks[2]...)
+	fakeFinal[0] = cellStreamChunkFinal
+	if _, err := decryptStream(key, joinStream(header, chunks[0], chunks[1], fakeFinal), nil); err == nil {
+		t.Fatal("expected error on chunk marked as final")
+	}
+	// data after final chunk ignored by default
+	trailing := bytes.NewReader(append(encrypted, 0))
+	reader, err := NewCellSealReader(key, nil, trailing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypted, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decrypted, data) || trailing.Len() != 1 {
+		t.Fatal("incorrect read of stream with data after final chunk")
+	}
+	// plaintext of final chunk not kept when stream has trailing data
+	reader, err = NewCellSealReaderWithEOFCheck(key, nil, bytes.NewReader(append(encrypted, 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range chunks[1:] {
+		if err := reader.readChunk(); err != nil {
+			t.Fatal(err)
+		}
+		// consumed by Read
+		reader.decrypted = nil
+	}
+	if err := reader.readChunk(); err != ErrCellStreamTrailingData {
+		t.Fatal("expected ErrCellStreamTrailingData", err)
+	}
+	if len(reader.decrypted) != 0 {
+		t.Fatal("final chunk stored after ErrCellStreamTrailingData")
+	}
+	// chunk from another stream with same key and context
+	_, otherChunks := streamChunks(encryptStream(key, data, nil, t))
+	if _, err := decryptStream(key, joinStream(header, chunks[0], otherChunks[1], chunks[2], chunks[3]), nil); err == nil {
+		t.Fatal("expected error on chunk from another stream")
+	}
+	// huge chunk length
+	invalidLength := append([]byte{}, chunks[0]...)
+	invalidLength[1], invalidLength[2], invalidLength[3], invalidLength[4] = 0xff, 0xff, 0xff, 0xff
+	if _, err := decryptStream(key, joinStream(header, invalidLength), nil); err != ErrCellStreamCorrupted {
+		t.Fatal("expected ErrCellStreamCorrupted", err)
+	}
+}
+
+func TestCellSealWriterClosed(t *testing.T) {
+	writer, err := NewCellSealWriter([]byte("key"), nil, ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := writer.Write([]byte("data")); err != ErrCellStreamClosed {
+		t.Fatal("expected ErrCellStreamClosed")
+	}
+	if _, err := NewCellSealWriter(nil, nil, ioutil.Discard); err != ErrMissingKey {
+		t.Fatal("expected ErrMissingKey")
+	}
+	if _, err := NewCellSealWriterWithChunkSize([]byte("key"), nil, ioutil.Discard, MaxCellStreamChunkSize+1); err != ErrCellStreamInvalidChunkSize {
+		t.Fatal("expected ErrCellStreamInvalidChunkSize")
+	}
+	var _ io.WriteCloser = writer
+}
+
+func BenchmarkCellSealStream(b *testing.B) {
+	key := []byte("key")
+	data := make([]byte, 1024*1024)
+	rand.Read(data)
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		output := &bytes.Buffer{}
+		writer, err := NewCellSealWriter(key, nil, output)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := writer.Write(data); err != nil {
+			b.Fatal(err)
+		}
+		if err := writer.Close(); err != nil {
+			b.Fatal(err)
+		}
+		reader, err := NewCellSealReader(key, nil, output)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := io.Copy(ioutil.Discard, reader); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -2,10 +2,13 @@ package gothemis
 
 import (
 	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
 	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/binary"
+	"encoding/hex"
 	"testing"
 
 	"github.com/cossacklabs/themis/gothemis/cell"
@@ -140,3 +143,331 @@ func BenchmarkThemisSeal(b *testing.B) {
 		}
 	}
 }
+
+func TestCellTokenProtect(t *testing.T) {
+	key := make([]byte, 20)
+	message := make([]byte, 100)
+	context := make([]byte, 100)
+	for i := 0; i < 1000; i++ {
+		rand.Read(key)
+		rand.Read(message)
+		rand.Read(context)
+		origCell := cell.New(key, cell.ModeTokenProtect)
+
+		encrypted, token, err := CellTokenProtectEncrypt(key, message, context)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(encrypted) != len(message) {
+			t.Fatal("encrypted data has incorrect length")
+		}
+		if len(token) != AuthSymMessageHeaderSize {
+			t.Fatal("token has incorrect length")
+		}
+		data, err := origCell.Unprotect(encrypted, token, context)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, message) {
+			t.Fatal("data not equal")
+		}
+		data, err = CellTokenProtectDecrypt(key, encrypted, token, context)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, message) {
+			t.Fatal("data not equal")
+		}
+
+		encrypted, token, err = origCell.Protect(message, context)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err = CellTokenProtectDecrypt(key, encrypted, token, context)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, message) {
+			t.Fatal("data not equal")
+		}
+
+		if _, err = CellTokenProtectDecrypt(key, encrypted, token[:len(token)-1], context); err != ErrInvalidAuthToken {
+			t.Fatal("expected ErrInvalidAuthToken on short token")
+		}
+		if _, err = CellTokenProtectDecrypt(key, encrypted[1:], token, context); err != ErrCellTruncated {
+			t.Fatal("expected ErrCellTruncated on data with incorrect length")
+		}
+	}
+}
+
+func TestCellContextImprint(t *testing.T) {
+	key := make([]byte, 20)
+	message := make([]byte, 100)
+	context := make([]byte, 100)
+	for i := 0; i < 1000; i++ {
+		rand.Read(key)
+		rand.Read(message)
+		rand.Read(context)
+		origCell := cell.New(key, cell.ModeContextImprint)
+
+		encrypted, err := CellContextImprintEncrypt(key, message, context)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(encrypted) != len(message) {
+			t.Fatal("encrypted data has incorrect length")
+		}
+		themisEncrypted, _, err := origCell.Protect(message, context)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(encrypted, themisEncrypted) {
+			t.Fatal("encrypted data not equal to themis output")
+		}
+		data, err := origCell.Unprotect(encrypted, nil, context)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, message) {
+			t.Fatal("data not equal")
+		}
+		data, err = CellContextImprintDecrypt(key, themisEncrypted, context)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, message) {
+			t.Fatal("data not equal")
+		}
+	}
+	if _, err := CellContextImprintEncrypt(key, message, nil); err != ErrMissingContext {
+		t.Fatal("expected ErrMissingContext")
+	}
+}
+
+func TestCellSealKeyLength(t *testing.T) {
+	key := []byte("some key")
+	message := []byte("some message")
+	context := []byte("some context")
+	origCell := cell.New(key, cell.ModeSeal)
+	for _, keyLength := range []uint32{SOTER_SYM_128_KEY_LENGTH, SOTER_SYM_192_KEY_LENGTH, SOTER_SYM_256_KEY_LENGTH} {
+		encrypted, err := CellSealEncryptWithKeyLength(key, message, context, keyLength)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if alg := binary.LittleEndian.Uint32(encrypted[:4]); alg != SOTER_SYM_AES_GCM|keyLength {
+			t.Fatalf("incorrect alg %x", alg)
+		}
+		data, err := CellSealDecrypt(key, encrypted, context)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, message) {
+			t.Fatal("data not equal")
+		}
+		data, err = origCell.Unprotect(encrypted, nil, context)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, message) {
+			t.Fatal("data not equal")
+		}
+	}
+	if _, err := CellSealEncryptWithKeyLength(key, message, context, 0x40); err != ErrCellBadAlgorithm {
+		t.Fatal("expected ErrCellBadAlgorithm")
+	}
+	if _, err := CellSealEncryptWithKeyLength(key, message, context, SOTER_SYM_PBKDF2|SOTER_SYM_256_KEY_LENGTH); err != ErrCellBadAlgorithm {
+		t.Fatal("expected ErrCellBadAlgorithm")
+	}
+
+	encrypted, err := CellSealEncrypt(key, message, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, alg := range []uint32{
+		SOTER_SYM_AES_CTR | SOTER_SYM_256_KEY_LENGTH,
+		SOTER_SYM_AES_GCM | 0x40,
+		SOTER_SYM_AES_GCM | 0x02000000 | SOTER_SYM_256_KEY_LENGTH,
+		SOTER_SYM_AES_GCM | SOTER_SYM_PBKDF2 | SOTER_SYM_256_KEY_LENGTH,
+	} {
+		binary.LittleEndian.PutUint32(encrypted[:4], alg)
+		if _, err := CellSealDecrypt(key, encrypted, context); err == nil {
+			t.Fatalf("expected error on alg %x", alg)
+		}
+	}
+}
+
+func TestCellSealDecryptInvalidData(t *testing.T) {
+	key := []byte("some key")
+	message := []byte("some message")
+	context := []byte("some context")
+	encrypted, err := CellSealEncrypt(key, message, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < len(encrypted); i++ {
+		if _, err := CellSealDecrypt(key, encrypted[:i], context); err != ErrCellTruncated {
+			t.Fatalf("expected ErrCellTruncated on length %d, took %v", i, err)
+		}
+	}
+	if _, err := CellSealDecrypt(key, append(encrypted, 0), context); err != ErrCellMalformed {
+		t.Fatal("expected ErrCellMalformed on data longer than declared")
+	}
+	if _, err := CellSealDecrypt(key, encrypted, nil); err != ErrCellAuthFailed {
+		t.Fatal("expected ErrCellAuthFailed on incorrect context")
+	}
+	if _, err := CellSealDecrypt([]byte("incorrect key"), encrypted, context); err != ErrCellAuthFailed {
+		t.Fatal("expected ErrCellAuthFailed on incorrect key")
+	}
+
+	withHeader := func(offset int, value uint32) []byte {
+		output := append([]byte{}, encrypted...)
+		binary.LittleEndian.PutUint32(output[offset:offset+4], value)
+		return output
+	}
+	// huge iv and auth tag lengths must not cause allocations
+	if _, err := CellSealDecrypt(key, withHeader(4, 0xffffffff), context); err != ErrCellMalformed {
+		t.Fatal("expected ErrCellMalformed on incorrect iv length")
+	}
+	if _, err := CellSealDecrypt(key, withHeader(8, 0xffffffff), context); err != ErrCellMalformed {
+		t.Fatal("expected ErrCellMalformed on incorrect auth tag length")
+	}
+	if _, err := CellSealDecrypt(key, withHeader(12, 0xffffffff), context); err != ErrCellTruncated {
+		t.Fatal("expected ErrCellTruncated on incorrect message length")
+	}
+	if _, err := CellSealDecrypt(key, withHeader(0, SOTER_SYM_AES_CTR|SOTER_SYM_256_KEY_LENGTH), context); err != ErrCellBadAlgorithm {
+		t.Fatal("expected ErrCellBadAlgorithm on incorrect algorithm")
+	}
+	if _, err := CellSealDecrypt(key, withHeader(0, THEMIS_AUTH_SYM_PASSPHRASE_ALG), context); err != ErrCellBadAlgorithm {
+		t.Fatal("expected ErrCellBadAlgorithm on incorrect kdf")
+	}
+
+	// random data must not cause panic
+	data := make([]byte, 100)
+	for i := 0; i < 1000; i++ {
+		rand.Read(data)
+		CellSealDecrypt(key, data, context)
+		CellSealDecryptWithPassphrase("passphrase", data, context)
+		CellTokenProtectDecrypt(key, data, data[:AuthSymMessageHeaderSize], context)
+	}
+}
+
+// legacyCellSeal encrypted with fixed IV 000102..0b, key "legacy key", context "legacy context" and message
+// "legacy message" with 64-bit message length in KDF context. Not output of real Themis 0.9.6: TestCellSealLegacyVector
+// checks it against independentLegacyCellSeal, built only from standard library primitives and Themis format
+// description, so vector doesn't depend on KDF and encryption code of this package
+const legacyCellSeal = "000101400c000000100000000e000000000102030405060708090a0bdb1e059a69cce08df6eeceeeb6dea0c0e71333f57ff953dba1fc1869aa78"
+
+// legacyCellSealEncrypt encrypt data with 64-bit message length in KDF context, the way Themis 0.9.6 is described
+// to do on 64-bit platforms
+func legacyCellSealEncrypt(key, data []byte, context Context, t *testing.T) []byte {
+	kdfKey := themisKDF(key, THEMIS_SYM_KDF_KEY_LABEL, [][]byte{messageToLegacyKDFContext(data), context})
+	encrypted, iv, tag, err := authSymEncrypt(kdfKey, data, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hdr, err := NewAuthSymMessageHeader(uint32(len(data)), iv, tag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, err := hdr.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(output, encrypted...)
+}
+
+// independentLegacyCellSeal encrypt message in Seal mode like Themis 0.9.6 on 64-bit platforms without code of this
+// package: HMAC-SHA256 KDF over counter, label, separator, 64-bit message length and context, then AES-256-GCM with
+// context as additional data and header {alg, IV length, tag length, message length, IV, tag}
+func independentLegacyCellSeal(key, message, context, iv []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte{0, 0, 0, 1})
+	mac.Write([]byte("Themis secure cell message key"))
+	mac.Write([]byte{0})
+	var length [8]byte
+	binary.LittleEndian.PutUint64(length[:], uint64(len(message)))
+	mac.Write(length[:])
+	mac.Write(context)
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		panic(err)
+	}
+	aesGCM, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(err)
+	}
+	sealed := aesGCM.Seal(nil, iv, message, context)
+	output := make([]byte, 16)
+	binary.LittleEndian.PutUint32(output[0:4], 0x40010100)
+	binary.LittleEndian.PutUint32(output[4:8], uint32(len(iv)))
+	binary.LittleEndian.PutUint32(output[8:12], uint32(aesGCM.Overhead()))
+	binary.LittleEndian.PutUint32(output[12:16], uint32(len(message)))
+	output = append(output, iv...)
+	output = append(output, sealed[len(message):]...)
+	return append(output, sealed[:len(message)]...)
+}
+
+func TestCellSealLegacyVector(t *testing.T) {
+	iv := []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}
+	expected := independentLegacyCellSeal([]byte("legacy key"), []byte("legacy message"), []byte("legacy context"), iv)
+	if hex.EncodeToString(expected) != legacyCellSeal {
+		t.Fatal("legacyCellSeal doesn't match independent encryption")
+	}
+}
+
+func TestCellSealLegacy(t *testing.T) {
+	key := []byte("legacy key")
+	context := []byte("legacy context")
+	message := []byte("legacy message")
+	encrypted, err := hex.DecodeString(legacyCellSeal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc, err := SealWithKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		data, err := CellSealDecrypt(key, encrypted, context)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, message) {
+			t.Fatal("data not equal")
+		}
+		data, err = sc.Decrypt(encrypted, context)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, message) {
+			t.Fatal("data not equal")
+		}
+		data, err = CellTokenProtectDecrypt(key, encrypted[AuthSymMessageHeaderSize:], encrypted[:AuthSymMessageHeaderSize], context)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, message) {
+			t.Fatal("data not equal")
+		}
+		if _, err := CellSealDecrypt(key, encrypted, nil); err != ErrCellAuthFailed {
+			t.Fatal("expected ErrCellAuthFailed")
+		}
+
+		message = make([]byte, 100)
+		rand.Read(message)
+		rand.Read(context)
+		encrypted = legacyCellSealEncrypt(key, message, context, t)
+	}
+
+	encrypted, err = symmetricEncryptMessageWithKDFContext(key, message, context, messageToLegacyKDFContext(message))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := CellContextImprintDecryptLegacy(key, encrypted, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, message) {
+		t.Fatal("data not equal")
+	}
+}
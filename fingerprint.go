@@ -0,0 +1,45 @@
+package gothemis
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// fingerprintShortSize count of bytes of fingerprint used in short form
+const fingerprintShortSize = 8
+
+// Fingerprint SHA-256 digest of public key in canonical Themis container with compressed point
+type Fingerprint [sha256.Size]byte
+
+// Hex return fingerprint encoded as hex
+func (f Fingerprint) Hex() string {
+	return hex.EncodeToString(f[:])
+}
+
+// Short return first fingerprintShortSize bytes of fingerprint encoded as hex. Suitable for logs
+func (f Fingerprint) Short() string {
+	return hex.EncodeToString(f[:fingerprintShortSize])
+}
+
+func (f Fingerprint) String() string {
+	return f.Hex()
+}
+
+// Fingerprint return digest of public key. Doesn't depend on point encoding used in source container because
+// Marshal always returns compressed point
+func (key *PublicECKey) Fingerprint() (Fingerprint, error) {
+	container, err := key.Marshal()
+	if err != nil {
+		return Fingerprint{}, err
+	}
+	return sha256.Sum256(container), nil
+}
+
+// Fingerprint return digest of public key that corresponds to private key
+func (key *PrivateECKey) Fingerprint() (Fingerprint, error) {
+	public, err := key.PublicKey()
+	if err != nil {
+		return Fingerprint{}, err
+	}
+	return public.Fingerprint()
+}
@@ -0,0 +1,62 @@
+package gothemis
+
+import (
+	"crypto/elliptic"
+	"strings"
+	"testing"
+)
+
+func TestFingerprint(t *testing.T) {
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		kp, err := NewECKeyPairWithCurve(curve)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fingerprint, err := kp.Public.Fingerprint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		privateFingerprint, err := kp.Private.Fingerprint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fingerprint != privateFingerprint {
+			t.Fatal("fingerprints of private and public keys not equal")
+		}
+
+		uncompressedBytes, err := kp.Public.MarshalUncompressed()
+		if err != nil {
+			t.Fatal(err)
+		}
+		uncompressed, err := UnmarshalThemisECPublicKey(uncompressedBytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		uncompressedFingerprint, err := uncompressed.Fingerprint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fingerprint != uncompressedFingerprint {
+			t.Fatal("fingerprint depends on point encoding")
+		}
+
+		if len(fingerprint.Hex()) != 64 || fingerprint.String() != fingerprint.Hex() {
+			t.Fatal("incorrect hex form", fingerprint.Hex())
+		}
+		if len(fingerprint.Short()) != 2*fingerprintShortSize || !strings.HasPrefix(fingerprint.Hex(), fingerprint.Short()) {
+			t.Fatal("incorrect short form", fingerprint.Short())
+		}
+
+		other, err := NewECKeyPairWithCurve(curve)
+		if err != nil {
+			t.Fatal(err)
+		}
+		otherFingerprint, err := other.Public.Fingerprint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if otherFingerprint == fingerprint {
+			t.Fatal("different keys have same fingerprints")
+		}
+	}
+}
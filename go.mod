@@ -1,6 +1,7 @@
 module github.com/lagovas/gothemis
 
-go 1.15
+// Go 1.20 is the minimum version: Secure Message and EC key parsing use crypto/ecdh
+go 1.20
 
 require (
 	git.apache.org/thrift.git v0.12.0 // indirect
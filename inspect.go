@@ -0,0 +1,191 @@
+package gothemis
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+// ObjectKind type of Themis object detected by InspectCell
+type ObjectKind int
+
+// Kinds of objects recognized by InspectCell
+const (
+	ObjectUnknown ObjectKind = iota
+	ObjectCellSeal
+	ObjectCellSealPassphrase
+	ObjectSecureMessageEncrypted
+	ObjectSecureMessageSigned
+	ObjectAcraStruct
+)
+
+func (kind ObjectKind) String() string {
+	switch kind {
+	case ObjectCellSeal:
+		return "Secure Cell Seal"
+	case ObjectCellSealPassphrase:
+		return "Secure Cell Seal with passphrase"
+	case ObjectSecureMessageEncrypted:
+		return "encrypted Secure Message"
+	case ObjectSecureMessageSigned:
+		return "signed Secure Message"
+	case ObjectAcraStruct:
+		return "AcraStruct"
+	}
+	return "unknown"
+}
+
+// CellInfo describes Themis object without decryption. Cell related fields filled only for Secure Cells
+type CellInfo struct {
+	Kind ObjectKind
+	// Algorithm raw alg field of Secure Cell header
+	Algorithm uint32
+	// KeyLength in bits taken from Algorithm
+	KeyLength int
+	// KDF bits of Algorithm, SOTER_SYM_NOKDF or SOTER_SYM_PBKDF2
+	KDF           uint32
+	IVLength      int
+	AuthTagLength int
+	// PBKDF2 parameters of passphrase cell, nil if can't be parsed
+	PBKDF2 *PBKDF2Context
+	// MessageType type of Secure Message
+	MessageType uint32
+	// MessageLength length of payload declared in header
+	MessageLength uint64
+	// PayloadLength real length of payload
+	PayloadLength uint64
+	// Consistent is true if declared lengths match real length of data
+	Consistent bool
+	// Inner describes Secure Cell wrapped into encrypted Secure Message or AcraStruct
+	Inner *CellInfo
+}
+
+var ErrUnknownObject = errors.New("data is not a known Themis object")
+
+// InspectCell detects kind of Themis object stored in data and parses its headers without key. Supports Secure Cells
+// in Seal mode with key or passphrase, Secure Messages and AcraStructs. Returns ErrUnknownObject for other data,
+// including Token Protect and Context Imprint cells that have no own header
+func InspectCell(data []byte) (*CellInfo, error) {
+	if len(data) >= len(TagBegin) && bytes.Equal(data[:len(TagBegin)], TagBegin) {
+		return inspectAcraStruct(data)
+	}
+	if len(data) < 4 {
+		return nil, ErrUnknownObject
+	}
+	tag := binary.LittleEndian.Uint32(data[:4])
+	switch {
+	case IsSecureMessageEncrypted(tag):
+		return inspectSecureMessage(ObjectSecureMessageEncrypted, data)
+	case tag == THEMIS_SECURE_MESSAGE_EC_SIGNED || tag == THEMIS_SECURE_MESSAGE_RSA_SIGNED:
+		return inspectSecureMessage(ObjectSecureMessageSigned, data)
+	case validateAuthSymAlg(tag) == nil:
+		return inspectSecureCell(data)
+	}
+	return nil, ErrUnknownObject
+}
+
+func inspectSecureCell(data []byte) (*CellInfo, error) {
+	alg := binary.LittleEndian.Uint32(data[:4])
+	keyLength, err := soterAlgKeyLength(alg)
+	if err != nil {
+		return nil, err
+	}
+	info := &CellInfo{Kind: ObjectCellSeal, Algorithm: alg, KeyLength: keyLength * 8, KDF: alg & SOTER_SYM_KDF_MASK}
+	fieldsSize := authSymMessageHeaderFieldsSize
+	if info.KDF == SOTER_SYM_PBKDF2 {
+		info.Kind = ObjectCellSealPassphrase
+		fieldsSize = passphraseMessageHeaderFieldsSize
+	}
+	if len(data) < fieldsSize {
+		return nil, ErrCellTruncated
+	}
+	info.IVLength = int(binary.LittleEndian.Uint32(data[4:8]))
+	info.AuthTagLength = int(binary.LittleEndian.Uint32(data[8:12]))
+	info.MessageLength = uint64(binary.LittleEndian.Uint32(data[12:16]))
+	headerSize := uint64(fieldsSize) + uint64(info.IVLength) + uint64(info.AuthTagLength)
+	if info.Kind == ObjectCellSealPassphrase {
+		kdfContextLength := uint64(binary.LittleEndian.Uint32(data[16:20]))
+		kdfContextStart := headerSize
+		headerSize += kdfContextLength
+		if headerSize <= uint64(len(data)) {
+			info.PBKDF2, _ = UnmarshalPBKDF2Context(data[kdfContextStart:headerSize])
+		}
+	}
+	if headerSize <= uint64(len(data)) {
+		info.PayloadLength = uint64(len(data)) - headerSize
+	}
+	info.Consistent = info.IVLength == THEMIS_AUTH_SYM_IV_LENGTH &&
+		info.AuthTagLength == THEMIS_AUTH_SYM_AUTH_TAG_LENGTH &&
+		headerSize+info.MessageLength == uint64(len(data)) &&
+		(info.Kind == ObjectCellSeal || info.PBKDF2 != nil)
+	return info, nil
+}
+
+// inspectInnerCell inspects Secure Cell wrapped into other object. Nested objects other than cells not allowed
+func inspectInnerCell(data []byte) (*CellInfo, error) {
+	if len(data) < 4 || validateAuthSymAlg(binary.LittleEndian.Uint32(data[:4])) != nil {
+		return nil, ErrUnknownObject
+	}
+	return inspectSecureCell(data)
+}
+
+func inspectSecureMessage(kind ObjectKind, data []byte) (*CellInfo, error) {
+	if len(data) < themisSecureMessageHeaderSize {
+		return nil, ErrInvalidMessageLength
+	}
+	info := &CellInfo{Kind: kind, MessageType: binary.LittleEndian.Uint32(data[:4])}
+	info.PayloadLength = uint64(len(data) - themisSecureMessageHeaderSize)
+	if kind == ObjectSecureMessageSigned {
+		if len(data) < signedMessageStaticOverhead {
+			return nil, ErrInvalidMessageLength
+		}
+		info.MessageLength = uint64(binary.LittleEndian.Uint32(data[4:8]))
+		signatureLength := uint64(binary.LittleEndian.Uint32(data[8:12]))
+		info.PayloadLength = uint64(len(data) - signedMessageStaticOverhead)
+		info.Consistent = info.MessageLength+signatureLength == info.PayloadLength
+		return info, nil
+	}
+	// length of encrypted message includes header
+	messageSize := uint64(binary.LittleEndian.Uint32(data[4:8]))
+	if messageSize >= themisSecureMessageHeaderSize {
+		info.MessageLength = messageSize - themisSecureMessageHeaderSize
+	}
+	info.Consistent = messageSize == uint64(len(data))
+	cellStart := uint64(themisSecureMessageHeaderSize)
+	if info.MessageType == ThemisSecureMessageRSAEncrypted {
+		// Secure Cell placed after length of encrypted password and password itself
+		if len(data) < rsaSecureMessageHeaderSize {
+			info.Consistent = false
+			return info, nil
+		}
+		cellStart = rsaSecureMessageHeaderSize + uint64(binary.LittleEndian.Uint32(data[8:12]))
+		if cellStart > uint64(len(data)) {
+			info.Consistent = false
+			return info, nil
+		}
+	}
+	if inner, err := inspectInnerCell(data[cellStart:]); err == nil {
+		info.Inner = inner
+		info.Consistent = info.Consistent && inner.Consistent
+	} else {
+		info.Consistent = false
+	}
+	return info, nil
+}
+
+func inspectAcraStruct(data []byte) (*CellInfo, error) {
+	if len(data) < GetMinAcraStructLength() {
+		return nil, ErrIncorrectAcraStructLength
+	}
+	info := &CellInfo{Kind: ObjectAcraStruct}
+	info.MessageLength = binary.LittleEndian.Uint64(data[GetMinAcraStructLength()-DataLengthSize : GetMinAcraStructLength()])
+	info.PayloadLength = uint64(len(data) - GetMinAcraStructLength())
+	info.Consistent = ValidateAcraStructLength(data) == nil
+	if inner, err := inspectInnerCell(data[GetMinAcraStructLength():]); err == nil {
+		info.Inner = inner
+		info.Consistent = info.Consistent && inner.Consistent
+	} else {
+		info.Consistent = false
+	}
+	return info, nil
+}
@@ -0,0 +1,139 @@
+package gothemis
+
+import (
+	"crypto/rand"
+	"testing"
+)
+
+func TestInspectCell(t *testing.T) {
+	key := []byte("some key")
+	message := []byte("some message")
+	context := []byte("some context")
+
+	encrypted, err := CellSealEncryptWithKeyLength(key, message, context, SOTER_SYM_128_KEY_LENGTH)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := InspectCell(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Kind != ObjectCellSeal || info.KeyLength != 128 || info.KDF != SOTER_SYM_NOKDF || !info.Consistent {
+		t.Fatalf("incorrect info %+v", info)
+	}
+	if info.IVLength != THEMIS_AUTH_SYM_IV_LENGTH || info.AuthTagLength != THEMIS_AUTH_SYM_AUTH_TAG_LENGTH {
+		t.Fatalf("incorrect info %+v", info)
+	}
+	if info.MessageLength != uint64(len(message)) || info.PayloadLength != uint64(len(message)) {
+		t.Fatalf("incorrect info %+v", info)
+	}
+	info, err = InspectCell(encrypted[:len(encrypted)-1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Consistent || info.PayloadLength != uint64(len(message)-1) {
+		t.Fatalf("incorrect info of truncated cell %+v", info)
+	}
+
+	encrypted, err = cellSealEncryptWithPassphrase([]byte("passphrase"), message, context, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err = InspectCell(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Kind != ObjectCellSealPassphrase || info.KDF != SOTER_SYM_PBKDF2 || info.KeyLength != 256 || !info.Consistent {
+		t.Fatalf("incorrect info %+v", info)
+	}
+	if info.PBKDF2 == nil || info.PBKDF2.IterationCount != 10 || len(info.PBKDF2.Salt) != THEMIS_AUTH_SYM_PBKDF2_SALT_LENGTH {
+		t.Fatalf("incorrect PBKDF2 context %+v", info.PBKDF2)
+	}
+
+	alice, err := NewECKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, err := NewECKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sm, err := NewSecureMessage(alice.Private, bob.Public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrapped, err := sm.Wrap(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err = InspectCell(wrapped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Kind != ObjectSecureMessageEncrypted || info.MessageType != ThemisSecureMessageECEncrypted || !info.Consistent {
+		t.Fatalf("incorrect info %+v", info)
+	}
+	if info.Inner == nil || info.Inner.Kind != ObjectCellSeal || info.Inner.MessageLength != uint64(len(message)) {
+		t.Fatalf("incorrect inner info %+v", info.Inner)
+	}
+
+	rsaKeypair, err := NewRSAKeyPairWithSize(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaSM, err := NewSecureMessageRSA(nil, rsaKeypair.Public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrapped, err = rsaSM.Wrap(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err = InspectCell(wrapped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.MessageType != ThemisSecureMessageRSAEncrypted || !info.Consistent || info.Inner == nil || info.Inner.MessageLength != uint64(len(message)) {
+		t.Fatalf("incorrect info %+v", info)
+	}
+
+	signed, err := Sign(message, alice.Private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err = InspectCell(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Kind != ObjectSecureMessageSigned || info.MessageLength != uint64(len(message)) || !info.Consistent {
+		t.Fatalf("incorrect info %+v", info)
+	}
+
+	acrastruct, err := CreateAcrastruct(message, bob.Public, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err = InspectCell(acrastruct)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Kind != ObjectAcraStruct || !info.Consistent || info.Inner == nil || info.Inner.Kind != ObjectCellSeal {
+		t.Fatalf("incorrect info %+v", info)
+	}
+	info, err = InspectCell(acrastruct[:len(acrastruct)-1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Consistent {
+		t.Fatal("truncated AcraStruct reported as consistent")
+	}
+
+	if _, err := InspectCell([]byte("some data")); err != ErrUnknownObject {
+		t.Fatal("expected ErrUnknownObject")
+	}
+	data := make([]byte, 100)
+	for i := 0; i < 1000; i++ {
+		rand.Read(data)
+		InspectCell(data)
+	}
+}
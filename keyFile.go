@@ -0,0 +1,98 @@
+package gothemis
+
+import (
+	"bytes"
+	"encoding/pem"
+	"errors"
+	"io"
+	"io/ioutil"
+)
+
+// Encrypted private key has next structure:
+// magic[4]byte, version[uint8], Secure Cell Seal with passphrase
+// Secure Cell encrypts private key container from PrivateECKey.Marshal with header as context, so header can't be
+// changed without decryption error
+const (
+	encryptedKeyMagic          = "TEPK"
+	encryptedKeyVersion1       = 1
+	encryptedKeyHeaderSize     = len(encryptedKeyMagic) + 1
+	encryptedKeyCurrentVersion = encryptedKeyVersion1
+	// maxEncryptedKeySize limits size of data read by LoadPrivateKeyEncrypted. Real keys are much smaller
+	maxEncryptedKeySize = 64 * 1024
+)
+
+// PEMEncryptedPrivateKeyType PEM block type used by SavePrivateKeyEncryptedPEM
+const PEMEncryptedPrivateKeyType = "THEMIS ENCRYPTED PRIVATE KEY"
+
+var (
+	ErrInvalidEncryptedKey            = errors.New("incorrect encrypted private key data")
+	ErrUnsupportedEncryptedKeyVersion = errors.New("unsupported version of encrypted private key")
+)
+
+func encryptPrivateKey(key *PrivateECKey, passphrase string) ([]byte, error) {
+	container, err := key.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	defer Zeroize(container)
+	header := append([]byte(encryptedKeyMagic), encryptedKeyCurrentVersion)
+	encrypted, err := CellSealEncryptWithPassphrase(passphrase, container, header)
+	if err != nil {
+		return nil, err
+	}
+	return append(header, encrypted...), nil
+}
+
+func decryptPrivateKey(data []byte, passphrase string) (*PrivateECKey, error) {
+	if len(data) < encryptedKeyHeaderSize || !bytes.Equal(data[:len(encryptedKeyMagic)], []byte(encryptedKeyMagic)) {
+		return nil, ErrInvalidEncryptedKey
+	}
+	if data[len(encryptedKeyMagic)] != encryptedKeyVersion1 {
+		return nil, ErrUnsupportedEncryptedKeyVersion
+	}
+	container, err := CellSealDecryptWithPassphrase(passphrase, data[encryptedKeyHeaderSize:], data[:encryptedKeyHeaderSize])
+	if err != nil {
+		return nil, err
+	}
+	defer Zeroize(container)
+	return UnmarshalThemisECPrivateKey(container)
+}
+
+// SavePrivateKeyEncrypted writes private key encrypted with passphrase to w in binary format
+func SavePrivateKeyEncrypted(w io.Writer, key *PrivateECKey, passphrase string) error {
+	encrypted, err := encryptPrivateKey(key, passphrase)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(encrypted)
+	return err
+}
+
+// SavePrivateKeyEncryptedPEM writes private key encrypted with passphrase to w in PEM block with
+// PEMEncryptedPrivateKeyType. Suitable for text files
+func SavePrivateKeyEncryptedPEM(w io.Writer, key *PrivateECKey, passphrase string) error {
+	encrypted, err := encryptPrivateKey(key, passphrase)
+	if err != nil {
+		return err
+	}
+	return pem.Encode(w, &pem.Block{Type: PEMEncryptedPrivateKeyType, Bytes: encrypted})
+}
+
+// LoadPrivateKeyEncrypted reads private key saved by SavePrivateKeyEncrypted or SavePrivateKeyEncryptedPEM from r
+// and decrypts it with passphrase
+func LoadPrivateKeyEncrypted(r io.Reader, passphrase string) (*PrivateECKey, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(r, maxEncryptedKeySize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxEncryptedKeySize {
+		return nil, ErrInvalidEncryptedKey
+	}
+	if block, _ := pem.Decode(data); block != nil {
+		if block.Type != PEMEncryptedPrivateKeyType {
+			return nil, ErrInvalidEncryptedKey
+		}
+		data = block.Bytes
+	}
+	return decryptPrivateKey(data, passphrase)
+}
@@ -0,0 +1,83 @@
+package gothemis
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPrivateKeyEncrypted(t *testing.T) {
+	kp, err := NewECKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := kp.Private.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	passphrase := "some passphrase"
+	for _, save := range []func(*bytes.Buffer) error{
+		func(output *bytes.Buffer) error { return SavePrivateKeyEncrypted(output, kp.Private, passphrase) },
+		func(output *bytes.Buffer) error { return SavePrivateKeyEncryptedPEM(output, kp.Private, passphrase) },
+	} {
+		output := &bytes.Buffer{}
+		if err := save(output); err != nil {
+			t.Fatal(err)
+		}
+		saved := output.Bytes()
+		if bytes.Contains(saved, expected[ecKeyHeaderSize:]) {
+			t.Fatal("private key saved unencrypted")
+		}
+		private, err := LoadPrivateKeyEncrypted(bytes.NewReader(saved), passphrase)
+		if err != nil {
+			t.Fatal(err)
+		}
+		encoded, err := private.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(encoded, expected) {
+			t.Fatal("loaded key not equal to saved key")
+		}
+		if _, err := LoadPrivateKeyEncrypted(bytes.NewReader(saved), "incorrect passphrase"); err == nil {
+			t.Fatal("expected error with incorrect passphrase")
+		}
+	}
+}
+
+func TestPrivateKeyEncryptedInvalid(t *testing.T) {
+	kp, err := NewECKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	passphrase := "some passphrase"
+	output := &bytes.Buffer{}
+	if err := SavePrivateKeyEncrypted(output, kp.Private, passphrase); err != nil {
+		t.Fatal(err)
+	}
+	saved := output.Bytes()
+	if string(saved[:len(encryptedKeyMagic)]) != encryptedKeyMagic || saved[len(encryptedKeyMagic)] != encryptedKeyCurrentVersion {
+		t.Fatal("incorrect header")
+	}
+
+	unsupported := append([]byte{}, saved...)
+	unsupported[len(encryptedKeyMagic)] = encryptedKeyCurrentVersion + 1
+	if _, err := LoadPrivateKeyEncrypted(bytes.NewReader(unsupported), passphrase); err != ErrUnsupportedEncryptedKeyVersion {
+		t.Fatal("expected ErrUnsupportedEncryptedKeyVersion", err)
+	}
+	if _, err := LoadPrivateKeyEncrypted(bytes.NewReader(saved[:len(encryptedKeyMagic)]), passphrase); err != ErrInvalidEncryptedKey {
+		t.Fatal("expected ErrInvalidEncryptedKey", err)
+	}
+	if _, err := LoadPrivateKeyEncrypted(bytes.NewReader(saved[:len(saved)-1]), passphrase); err == nil {
+		t.Fatal("expected error on truncated key")
+	}
+	privatePEM, err := kp.Private.MarshalPEM()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadPrivateKeyEncrypted(bytes.NewReader(privatePEM), passphrase); err != ErrInvalidEncryptedKey {
+		t.Fatal("expected ErrInvalidEncryptedKey", err)
+	}
+	if _, err := LoadPrivateKeyEncrypted(bytes.NewReader(make([]byte, maxEncryptedKeySize+1)), passphrase); err != ErrInvalidEncryptedKey {
+		t.Fatal("expected ErrInvalidEncryptedKey", err)
+	}
+}
@@ -0,0 +1,73 @@
+package gothemis
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"errors"
+)
+
+// KeyKind kind of key stored in raw bytes
+type KeyKind int
+
+// Kinds of keys detected by DetectKeyKind
+const (
+	KeyInvalid KeyKind = iota
+	KeyECPrivate
+	KeyECPublic
+	KeyRSAPrivate
+	KeyRSAPublic
+	KeySymmetric
+)
+
+func (kind KeyKind) String() string {
+	switch kind {
+	case KeyECPrivate:
+		return "EC private key"
+	case KeyECPublic:
+		return "EC public key"
+	case KeyRSAPrivate:
+		return "RSA private key"
+	case KeyRSAPublic:
+		return "RSA public key"
+	case KeySymmetric:
+		return "symmetric key"
+	}
+	return "invalid key"
+}
+
+var ErrEmptyKey = errors.New("empty key data")
+
+// DetectKeyKind return kind of key stored in raw and curve for EC keys. Asymmetric keys recognized by Themis
+// container tag and validated by size and crc like Themis keys helpers do, other non empty data treated as symmetric
+// key. Returns KeyInvalid with error if data has tag of asymmetric key but container is corrupted
+func DetectKeyKind(raw []byte) (KeyKind, elliptic.Curve, error) {
+	if len(raw) == 0 {
+		return KeyInvalid, nil, ErrEmptyKey
+	}
+	if len(raw) < ecKeyTagLength {
+		return KeySymmetric, nil, nil
+	}
+	prefix := raw[:ecKeyTagLength-1]
+	switch {
+	case bytes.Equal(prefix, ecPrivateKeyPrefix), bytes.Equal(prefix, ecPublicKeyPrefix):
+		tag, _, err := unmarshalECKeyHeader(raw)
+		if err != nil {
+			return KeyInvalid, nil, err
+		}
+		if bytes.Equal(prefix, ecPrivateKeyPrefix) {
+			return KeyECPrivate, TagToCurve(tag), nil
+		}
+		return KeyECPublic, TagToCurve(tag), nil
+	case bytes.Equal(prefix, rsaPrivateKeyPrefix):
+		if _, _, _, err := unmarshalRSAKeyHeader(raw, rsaPrivateKeyPrefix); err != nil {
+			return KeyInvalid, nil, err
+		}
+		return KeyRSAPrivate, nil, nil
+	case bytes.Equal(prefix, rsaPublicKeyPrefix):
+		if _, _, _, err := unmarshalRSAKeyHeader(raw, rsaPublicKeyPrefix); err != nil {
+			return KeyInvalid, nil, err
+		}
+		return KeyRSAPublic, nil, nil
+	}
+	return KeySymmetric, nil, nil
+}
@@ -0,0 +1,80 @@
+package gothemis
+
+import (
+	"crypto/elliptic"
+	"testing"
+
+	"github.com/cossacklabs/themis/gothemis/keys"
+)
+
+func TestDetectKeyKind(t *testing.T) {
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		kp, err := NewECKeyPairWithCurve(curve)
+		if err != nil {
+			t.Fatal(err)
+		}
+		themisKeypair, err := kp.ToThemisKeyPair()
+		if err != nil {
+			t.Fatal(err)
+		}
+		kind, keyCurve, err := DetectKeyKind(themisKeypair.Private.Value)
+		if err != nil || kind != KeyECPrivate || keyCurve != curve {
+			t.Fatal("incorrect kind of private key", kind, err)
+		}
+		kind, keyCurve, err = DetectKeyKind(themisKeypair.Public.Value)
+		if err != nil || kind != KeyECPublic || keyCurve != curve {
+			t.Fatal("incorrect kind of public key", kind, err)
+		}
+		themisKeypair.Public.Value[ecKeyHeaderSize] ^= 1
+		if kind, _, err := DetectKeyKind(themisKeypair.Public.Value); err != ErrInvalidCrc32Check || kind != KeyInvalid {
+			t.Fatal("expected ErrInvalidCrc32Check", kind, err)
+		}
+	}
+
+	rsaKeypair, err := keys.New(keys.TypeRSA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kind, _, err := DetectKeyKind(rsaKeypair.Private.Value); err != nil || kind != KeyRSAPrivate {
+		t.Fatal("incorrect kind of RSA private key", kind, err)
+	}
+	if kind, _, err := DetectKeyKind(rsaKeypair.Public.Value); err != nil || kind != KeyRSAPublic {
+		t.Fatal("incorrect kind of RSA public key", kind, err)
+	}
+
+	symmetricKey, err := keys.NewSymmetricKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kind, _, err := DetectKeyKind(symmetricKey.Value); err != nil || kind != KeySymmetric {
+		t.Fatal("incorrect kind of symmetric key", kind, err)
+	}
+	if _, _, err := DetectKeyKind(nil); err != ErrEmptyKey {
+		t.Fatal("expected ErrEmptyKey", err)
+	}
+	if kind, _, err := DetectKeyKind([]byte("UEC9 some data")); err != ErrInvalidKeyTag || kind != KeyInvalid {
+		t.Fatal("expected ErrInvalidKeyTag", kind, err)
+	}
+}
+
+func TestPrivateECKeyPublicKey(t *testing.T) {
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		kp, err := NewECKeyPairWithCurve(curve)
+		if err != nil {
+			t.Fatal(err)
+		}
+		privateBytes, err := kp.Private.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		private, err := UnmarshalThemisECPrivateKey(privateBytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		public, err := private.PublicKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		testPublicKeysEqual(kp.Public, public, t)
+	}
+}
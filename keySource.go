@@ -0,0 +1,66 @@
+package gothemis
+
+import "errors"
+
+// PrivateKeySource provides private keys of clients used for decryption. Implemented by keystore.FilesystemKeyStore
+type PrivateKeySource interface {
+	// PrivateKeys return all private keys of client that may be used for decryption, current key first. Returned keys
+	// owned by caller: functions of this package wipe them with Zeroize after use, so source must return copies
+	PrivateKeys(clientID []byte) ([]*PrivateECKey, error)
+}
+
+var ErrNoPrivateKeys = errors.New("key source has no private keys for client")
+
+// zeroizePrivateKeys wipes keys loaded from PrivateKeySource
+func zeroizePrivateKeys(keys []*PrivateECKey) {
+	for _, key := range keys {
+		key.Zeroize()
+	}
+}
+
+// DecryptAcrastructWithKeySource decrypt AcraStruct with private keys of clientID from source. Keys tried in order
+// returned by source so AcraStructs encrypted with previous versions of keys still can be decrypted
+func DecryptAcrastructWithKeySource(data []byte, source PrivateKeySource, clientID, zone []byte) ([]byte, error) {
+	privateKeys, err := source.PrivateKeys(clientID)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroizePrivateKeys(privateKeys)
+	if len(privateKeys) == 0 {
+		return nil, ErrNoPrivateKeys
+	}
+	for _, privateKey := range privateKeys {
+		var decrypted []byte
+		decrypted, err = DecryptAcrastruct(data, privateKey, zone)
+		if err == nil {
+			return decrypted, nil
+		}
+	}
+	return nil, err
+}
+
+// UnwrapSecureMessageWithKeySource decrypt Secure Message from peer with private keys of clientID from source. Keys
+// tried in order returned by source
+func UnwrapSecureMessageWithKeySource(data []byte, source PrivateKeySource, clientID []byte, peerPublicKey *PublicECKey) ([]byte, error) {
+	privateKeys, err := source.PrivateKeys(clientID)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroizePrivateKeys(privateKeys)
+	if len(privateKeys) == 0 {
+		return nil, ErrNoPrivateKeys
+	}
+	for _, privateKey := range privateKeys {
+		var smessage *secureMessage
+		smessage, err = NewSecureMessage(privateKey, peerPublicKey)
+		if err != nil {
+			continue
+		}
+		var decrypted []byte
+		decrypted, err = smessage.Unwrap(data)
+		if err == nil {
+			return decrypted, nil
+		}
+	}
+	return nil, err
+}
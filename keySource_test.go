@@ -0,0 +1,122 @@
+package gothemis
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"testing"
+)
+
+// testKeySource stores private keys in memory and returns their copies like PrivateKeySource must
+type testKeySource struct {
+	keys map[string][]*PrivateECKey
+	// returned copies to check that caller wiped them
+	returned []*PrivateECKey
+}
+
+func newTestKeySource(clientID string, keys ...*PrivateECKey) *testKeySource {
+	return &testKeySource{keys: map[string][]*PrivateECKey{clientID: keys}}
+}
+
+func (source *testKeySource) PrivateKeys(clientID []byte) ([]*PrivateECKey, error) {
+	var keys []*PrivateECKey
+	for _, key := range source.keys[string(clientID)] {
+		data, err := key.Marshal()
+		if err != nil {
+			return nil, err
+		}
+		copied, err := UnmarshalThemisECPrivateKey(data)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, copied)
+	}
+	source.returned = append(source.returned, keys...)
+	return keys, nil
+}
+
+// checkZeroized fails test if some returned key not wiped
+func (source *testKeySource) checkZeroized(t *testing.T) {
+	for _, key := range source.returned {
+		if key.private.D.Sign() != 0 {
+			t.Fatal("private key from source not wiped")
+		}
+	}
+}
+
+func TestDecryptAcrastructWithKeySource(t *testing.T) {
+	oldKeypair, err := NewECKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	newKeypair, err := NewECKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := newTestKeySource("client", newKeypair.Private, oldKeypair.Private)
+	data := []byte("some data")
+	for _, public := range []*PublicECKey{oldKeypair.Public, newKeypair.Public} {
+		acrastruct, err := CreateAcrastruct(data, public, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decrypted, err := DecryptAcrastructWithKeySource(acrastruct, source, []byte("client"), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(decrypted, data) {
+			t.Fatal("decrypted data not equal to source data")
+		}
+		if _, err := DecryptAcrastructWithKeySource(acrastruct, source, []byte("other client"), nil); err != ErrNoPrivateKeys {
+			t.Fatal("expected ErrNoPrivateKeys", err)
+		}
+	}
+	otherKeypair, err := NewECKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	acrastruct, err := CreateAcrastruct(data, otherKeypair.Public, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecryptAcrastructWithKeySource(acrastruct, source, []byte("client"), nil); err == nil {
+		t.Fatal("expected error with unknown key")
+	}
+	source.checkZeroized(t)
+}
+
+func TestUnwrapSecureMessageWithKeySource(t *testing.T) {
+	peer, err := NewECKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldKeypair, err := NewECKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// keys on other curves skipped
+	newKeypair, err := NewECKeyPairWithCurve(elliptic.P384())
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := newTestKeySource("client", newKeypair.Private, oldKeypair.Private)
+	smessage, err := NewSecureMessage(peer.Private, oldKeypair.Public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte("some data")
+	wrapped, err := smessage.Wrap(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypted, err := UnwrapSecureMessageWithKeySource(wrapped, source, []byte("client"), peer.Public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decrypted, data) {
+		t.Fatal("decrypted data not equal to source data")
+	}
+	if _, err := UnwrapSecureMessageWithKeySource(wrapped, source, []byte("other client"), peer.Public); err != ErrNoPrivateKeys {
+		t.Fatal("expected ErrNoPrivateKeys", err)
+	}
+	source.checkZeroized(t)
+}
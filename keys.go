@@ -2,6 +2,7 @@ package gothemis
 
 import (
 	"bytes"
+	"crypto/ecdh"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
@@ -19,10 +20,9 @@ var (
 )
 
 const (
-	ec256KeySizeSuffix  = '2'
-	ec384KeySizeSuffix  = '3'
-	ec521KeySizeSuffix  = '5'
-	compressedPublicKey = true
+	ec256KeySizeSuffix = '2'
+	ec384KeySizeSuffix = '3'
+	ec521KeySizeSuffix = '5'
 )
 
 var sizeSuffixToCurve = map[byte]elliptic.Curve{
@@ -31,14 +31,34 @@ var sizeSuffixToCurve = map[byte]elliptic.Curve{
 	ec521KeySizeSuffix: elliptic.P521(),
 }
 
+var curveToSizeSuffix = map[elliptic.Curve]byte{
+	elliptic.P256(): ec256KeySizeSuffix,
+	elliptic.P384(): ec384KeySizeSuffix,
+	elliptic.P521(): ec521KeySizeSuffix,
+}
+
 func TagToCurve(tag []byte) elliptic.Curve {
 	return sizeSuffixToCurve[tag[ecKeyTagLength-1]]
 }
 
-var (
-	defaultPublicKeyTag  = append(ecPublicKeyPrefix, ec256KeySizeSuffix)
-	defaultPrivateKeyTag = append(ecPrivateKeyPrefix, ec256KeySizeSuffix)
-)
+var ErrUnsupportedCurve = errors.New("unsupported elliptic curve")
+
+// curveToTag return key tag with prefix for curve
+func curveToTag(prefix []byte, curve elliptic.Curve) ([ecKeyTagLength]byte, error) {
+	var tag [ecKeyTagLength]byte
+	suffix, ok := curveToSizeSuffix[curve]
+	if !ok {
+		return tag, ErrUnsupportedCurve
+	}
+	copy(tag[:], prefix)
+	tag[ecKeyTagLength-1] = suffix
+	return tag, nil
+}
+
+// curveKeySize return size in bytes of private key and coordinate of point on curve
+func curveKeySize(curve elliptic.Curve) int {
+	return (curve.Params().BitSize + 7) / 8
+}
 
 const (
 	// tag + size + crc
@@ -57,74 +77,125 @@ func (p *PublicECKey) Public() *ecdsa.PublicKey {
 	return &ecdsa.PublicKey{X: p.x, Y: p.y, Curve: curve}
 }
 
+// Marshal return public key in Themis container with compressed point as Themis generates
 func (key *PublicECKey) Marshal() ([]byte, error) {
-	curveKeySize := TagToCurve(key.tag[:]).Params().BitSize / 8
-	if !compressedPublicKey {
-		curveKeySize *= 2
-	}
-	output := make([]byte, ecKeyTagLength+8+curveKeySize+1)
-	copy(output[:ecKeyTagLength], key.tag[:])
-	var pubKey []byte
-	if compressedPublicKey {
-		pubKey = CompressNISTPublicKey(TagToCurve(key.tag[:]), key.x, key.y)
-	} else {
-		pubKey = elliptic.Marshal(TagToCurve(key.tag[:]), key.x, key.y)
-	}
+	return key.marshal(true)
+}
 
-	binary.BigEndian.PutUint32(output[ecKeyTagLength:ecKeyTagLength+4], uint32(len(pubKey)+ecKeyHeaderSize))
-	writer := bytes.NewBuffer(output[:12])
+// MarshalUncompressed return public key in Themis container with uncompressed point. Themis accepts both forms
+func (key *PublicECKey) MarshalUncompressed() ([]byte, error) {
+	return key.marshal(false)
+}
 
-	//if err := binary.Write(writer, binary.BigEndian, CompressNISTPublicKey(TagToCurve(key.tag), key.x, key.y)); err != nil {
-	if err := binary.Write(writer, binary.BigEndian, pubKey); err != nil {
-		return nil, err
+func (key *PublicECKey) marshal(compressed bool) ([]byte, error) {
+	curve := TagToCurve(key.tag[:])
+	var pubKey []byte
+	if compressed {
+		pubKey = CompressNISTPublicKey(curve, key.x, key.y)
+	} else {
+		pubKey = elliptic.Marshal(curve, key.x, key.y)
 	}
+	output := make([]byte, ecKeyHeaderSize+len(pubKey))
+	copy(output[:ecKeyTagLength], key.tag[:])
+	binary.BigEndian.PutUint32(output[ecKeyTagLength:ecKeyTagLength+4], uint32(len(output)))
+	copy(output[ecKeyHeaderSize:], pubKey)
 	h := NewCRC32()
 	h.Write(output)
 	binary.LittleEndian.PutUint32(output[ecKeyTagLength+4:ecKeyTagLength+8], h.Sum32())
 	return output, nil
-
 }
 
+// PrivateECKey EC private key stored in Themis container. JSON marshalling of key returns ErrPrivateKeyJSON so private
+// key isn't exported implicitly, wrap it in PrivateJWK to marshal it as JWK. UnmarshalJSON accepts private JWK
 type PrivateECKey struct {
 	tag     [ecKeyTagLength]byte
 	size    int32
 	private *ecdsa.PrivateKey
 }
 
+// Zeroize wipes private scalar. Copies of scalar made by crypto/ecdh, like one in Secure Message created with key,
+// are not reachable and stay in memory until collected
 func (key *PrivateECKey) Zeroize() {
-	key.private.D.Set(&big.Int{})
+	zeroizeBigInt(key.private.D)
+}
+
+var curveToECDH = map[elliptic.Curve]ecdh.Curve{
+	elliptic.P256(): ecdh.P256(),
+	elliptic.P384(): ecdh.P384(),
+	elliptic.P521(): ecdh.P521(),
 }
 
-func newPrivateECKeyFromBytes(c elliptic.Curve, d []byte) *ecdsa.PrivateKey {
+var ErrInvalidPrivateKey = errors.New("incorrect EC private key")
+var ErrInvalidPublicKey = errors.New("incorrect public key")
+
+// newPrivateECKeyFromBytes return private key with public point computed from d by constant-time crypto/ecdh. d may
+// have leading zero bytes as Themis private key container has
+func newPrivateECKeyFromBytes(c elliptic.Curve, d []byte) (*ecdsa.PrivateKey, error) {
+	curve, ok := curveToECDH[c]
+	if !ok {
+		return nil, ErrUnsupportedCurve
+	}
+	size := curveKeySize(c)
+	if len(d) < size {
+		return nil, ErrInvalidPrivateKey
+	}
+	for _, b := range d[:len(d)-size] {
+		if b != 0 {
+			return nil, ErrInvalidPrivateKey
+		}
+	}
+	scalar := d[len(d)-size:]
+	ecdhPrivate, err := curve.NewPrivateKey(scalar)
+	if err != nil {
+		return nil, ErrInvalidPrivateKey
+	}
+	// uncompressed point: 0x04 || x || y
+	point := ecdhPrivate.PublicKey().Bytes()
 	priv := new(ecdsa.PrivateKey)
 	priv.PublicKey.Curve = c
-	priv.D = new(big.Int).SetBytes(d)
-	priv.PublicKey.X, priv.PublicKey.Y = c.ScalarBaseMult(d)
-	return priv
+	priv.D = new(big.Int).SetBytes(scalar)
+	priv.PublicKey.X = new(big.Int).SetBytes(point[1 : 1+size])
+	priv.PublicKey.Y = new(big.Int).SetBytes(point[1+size:])
+	return priv, nil
 }
 
-func newPrivateECKey(privateKey *ecdsa.PrivateKey) *PrivateECKey {
-	private := &PrivateECKey{private: privateKey}
-	privateData := privateKey.Params().BitSize / 8
-	copy(private.tag[:], defaultPrivateKeyTag)
+func newPrivateECKey(privateKey *ecdsa.PrivateKey) (*PrivateECKey, error) {
+	tag, err := curveToTag(ecPrivateKeyPrefix, privateKey.Curve)
+	if err != nil {
+		return nil, err
+	}
+	private := &PrivateECKey{private: privateKey, tag: tag}
+	privateData := curveKeySize(privateKey.Curve)
 	// +1 due to a historical mistake
 	private.size = int32(privateData + ecKeyHeaderSize + 1)
-	return private
+	return private, nil
 }
 
-func newPublicECKey(privateKey *ecdsa.PrivateKey) *PublicECKey {
-	public := &PublicECKey{}
-	data := CompressNISTPublicKey(elliptic.P256(), privateKey.X, privateKey.Y)
-	copy(public.tag[:], defaultPublicKeyTag)
+func newPublicECKey(publicKey *ecdsa.PublicKey) (*PublicECKey, error) {
+	tag, err := curveToTag(ecPublicKeyPrefix, publicKey.Curve)
+	if err != nil {
+		return nil, err
+	}
+	public := &PublicECKey{tag: tag}
+	data := CompressNISTPublicKey(publicKey.Curve, publicKey.X, publicKey.Y)
 	public.size = int32(len(data) + ecKeyHeaderSize)
-	public.x = privateKey.X
-	public.y = privateKey.Y
-	return public
+	public.x = publicKey.X
+	public.y = publicKey.Y
+	return public, nil
+}
+
+// PublicKey return public key that corresponds to private key
+func (key *PrivateECKey) PublicKey() (*PublicECKey, error) {
+	return newPublicECKey(&ecdsa.PublicKey{
+		Curve: key.private.Curve,
+		X:     new(big.Int).Set(key.private.X),
+		Y:     new(big.Int).Set(key.private.Y),
+	})
 }
 
 func (key *PrivateECKey) Marshal() ([]byte, error) {
 	// +1 due to a historical mistake. more below
-	privateKeySize := key.private.Params().BitSize / 8
+	privateKeySize := curveKeySize(key.private.Curve)
 	output := make([]byte, ecKeyHeaderSize+privateKeySize+1)
 	copy(output[:ecKeyTagLength], key.tag[:])
 	binary.BigEndian.PutUint32(output[ecKeyTagLength:ecKeyTagLength+4], uint32(key.size))
@@ -164,13 +235,28 @@ func (k *KeyPair) ToThemisKeyPair()(*keys.Keypair, error){
 		Public:	&keys.PublicKey{Value: publicKey}}, nil
 }
 
+// NewECKeyPair generate key pair on P-256 curve
 func NewECKeyPair() (*KeyPair, error) {
-	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	return NewECKeyPairWithCurve(elliptic.P256())
+}
+
+// NewECKeyPairWithCurve generate key pair on one of P-256, P-384 or P-521 curves
+func NewECKeyPairWithCurve(curve elliptic.Curve) (*KeyPair, error) {
+	if _, ok := curveToSizeSuffix[curve]; !ok {
+		return nil, ErrUnsupportedCurve
+	}
+	privateKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	private, err := newPrivateECKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	public, err := newPublicECKey(&privateKey.PublicKey)
 	if err != nil {
 		return nil, err
 	}
-	private := newPrivateECKey(privateKey)
-	public := newPublicECKey(privateKey)
 	return &KeyPair{Public: public, Private: private}, nil
 }
 
@@ -182,9 +268,20 @@ func unmarshalECKeyHeader(rawkeydata []byte) ([]byte, int32, error) {
 	if len(rawkeydata) < ecKeyHeaderSize {
 		return nil, 0, ErrInvalidKeyDataLength
 	}
-	if !bytes.Equal(rawkeydata[:ecKeyTagLength], defaultPublicKeyTag) && !bytes.Equal(rawkeydata[:ecKeyTagLength], defaultPrivateKeyTag) {
+	if !bytes.Equal(rawkeydata[:ecKeyTagLength-1], ecPublicKeyPrefix) && !bytes.Equal(rawkeydata[:ecKeyTagLength-1], ecPrivateKeyPrefix) {
+		return []byte{}, 0, ErrInvalidKeyTag
+	}
+	if _, ok := sizeSuffixToCurve[rawkeydata[ecKeyTagLength-1]]; !ok {
 		return []byte{}, 0, ErrInvalidKeyTag
 	}
+	return unmarshalKeyContainer(rawkeydata)
+}
+
+// unmarshalKeyContainer validate size and crc of soter key container and return its tag and size
+func unmarshalKeyContainer(rawkeydata []byte) ([]byte, int32, error) {
+	if len(rawkeydata) < ecKeyHeaderSize {
+		return nil, 0, ErrInvalidKeyDataLength
+	}
 	dataLength := int(binary.BigEndian.Uint32(rawkeydata[ecKeyTagLength : ecKeyTagLength+4]))
 	if dataLength != len(rawkeydata[ecKeyHeaderSize:])+ecKeyHeaderSize {
 		return []byte{}, 0, ErrInvalidKeyDataLength
@@ -208,6 +305,10 @@ func UnmarshalThemisECPublicKey(rawKey []byte) (*PublicECKey, error) {
 	key := &PublicECKey{}
 	copy(key.tag[:], tag)
 	key.size = size
+	keySize := curveKeySize(TagToCurve(tag))
+	if len(rawKey[ecKeyHeaderSize:]) != keySize+1 && len(rawKey[ecKeyHeaderSize:]) != 2*keySize+1 {
+		return nil, ErrInvalidKeyDataLength
+	}
 	var x, y *big.Int
 	if rawKey[ecKeyHeaderSize] == 4 {
 		x, y = elliptic.Unmarshal(TagToCurve(key.tag[:]), rawKey[ecKeyHeaderSize:])
@@ -234,15 +335,16 @@ func UnmarshalThemisECPrivateKey(rawKey []byte) (*PrivateECKey, error) {
 	}
 
 	curve := TagToCurve(tag)
-	private := newPrivateECKeyFromBytes(curve, rawKey[ecKeyHeaderSize:])
+	if len(rawKey[ecKeyHeaderSize:]) != curveKeySize(curve)+1 {
+		return nil, ErrInvalidKeyDataLength
+	}
+	private, err := newPrivateECKeyFromBytes(curve, rawKey[ecKeyHeaderSize:])
+	if err != nil {
+		return nil, err
+	}
 	key := &PrivateECKey{private: private}
 	copy(key.tag[:], tag)
 	key.size = size
-	key.private.D = &big.Int{}
-	a := rawKey[ecKeyHeaderSize:]
-	_ = a
-	key.private.D.SetBytes(rawKey[ecKeyHeaderSize:])
-	//key.d = rawKey[ecKeyHeaderSize:]
 
 	return key, nil
 }
@@ -0,0 +1,67 @@
+package gothemis
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"io"
+	"math/big"
+)
+
+// FromECDSAPrivateKey return Themis private key with tag for curve of key. Public point validated against private
+// scalar if set. Key copied so later changes of key don't affect result
+func FromECDSAPrivateKey(key *ecdsa.PrivateKey) (*PrivateECKey, error) {
+	if key == nil || key.D == nil {
+		return nil, ErrInvalidPrivateKey
+	}
+	if _, ok := curveToECDH[key.Curve]; !ok {
+		return nil, ErrUnsupportedCurve
+	}
+	scalar := NewSecretBytes(curveKeySize(key.Curve))
+	defer scalar.Destroy()
+	if key.D.Sign() <= 0 || key.D.BitLen() > scalar.Len()*8 {
+		return nil, ErrInvalidPrivateKey
+	}
+	key.D.FillBytes(scalar.Bytes())
+	private, err := newPrivateECKeyFromBytes(key.Curve, scalar.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	if key.X != nil && key.Y != nil && (key.X.Cmp(private.X) != 0 || key.Y.Cmp(private.Y) != 0) {
+		return nil, ErrInvalidPrivateKey
+	}
+	return newPrivateECKey(private)
+}
+
+// FromECDSAPublicKey return Themis public key with tag for curve of key. Returns ErrInvalidPublicKey if point is not
+// on curve. Key copied so later changes of key don't affect result
+func FromECDSAPublicKey(key *ecdsa.PublicKey) (*PublicECKey, error) {
+	if key == nil || key.X == nil || key.Y == nil {
+		return nil, ErrInvalidPublicKey
+	}
+	if _, ok := curveToECDH[key.Curve]; !ok {
+		return nil, ErrUnsupportedCurve
+	}
+	if !key.Curve.IsOnCurve(key.X, key.Y) {
+		return nil, ErrInvalidPublicKey
+	}
+	return newPublicECKey(&ecdsa.PublicKey{
+		Curve: key.Curve,
+		X:     new(big.Int).Set(key.X),
+		Y:     new(big.Int).Set(key.Y),
+	})
+}
+
+// Public return copy of *ecdsa.PublicKey of key so caller can't change key through it. Implements crypto.Signer
+func (key *PrivateECKey) Public() crypto.PublicKey {
+	return &ecdsa.PublicKey{
+		Curve: key.private.Curve,
+		X:     new(big.Int).Set(key.private.X),
+		Y:     new(big.Int).Set(key.private.Y),
+	}
+}
+
+// Sign digest with ECDSA and return ASN.1 DER encoded signature, same encoding as Themis uses. digest must be
+// already hashed, opts only informs about hash function. Implements crypto.Signer
+func (key *PrivateECKey) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return key.private.Sign(rand, digest, opts)
+}
@@ -0,0 +1,150 @@
+package gothemis
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestPrivateECKeySigner(t *testing.T) {
+	kp, err := NewECKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var signer crypto.Signer = kp.Private
+	public, ok := signer.Public().(*ecdsa.PublicKey)
+	if !ok || public.X.Cmp(kp.Public.x) != 0 || public.Y.Cmp(kp.Public.y) != 0 {
+		t.Fatal("incorrect public key of signer")
+	}
+	// changes of returned key don't affect signer
+	signer.Public().(*ecdsa.PublicKey).X.SetInt64(1)
+	if signer.Public().(*ecdsa.PublicKey).X.Cmp(kp.Public.x) != 0 {
+		t.Fatal("public key of signer changed through Public")
+	}
+	digest := sha256.Sum256([]byte("some data"))
+	signature, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ecdsa.VerifyASN1(public, digest[:], signature) {
+		t.Fatal("invalid signature")
+	}
+
+	// self-signed certificate signed through crypto.Signer
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "gothemis"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, signer.Public(), signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := certificate.CheckSignatureFrom(certificate); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFromECDSAKeys(t *testing.T) {
+	testCases := []struct {
+		curve      elliptic.Curve
+		publicTag  string
+		privateTag string
+	}{
+		{elliptic.P256(), "UEC2", "REC2"},
+		{elliptic.P384(), "UEC3", "REC3"},
+		{elliptic.P521(), "UEC5", "REC5"},
+	}
+	for _, tcase := range testCases {
+		key, err := ecdsa.GenerateKey(tcase.curve, rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		private, err := FromECDSAPrivateKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		public, err := FromECDSAPublicKey(&key.PublicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(private.tag[:]) != tcase.privateTag || string(public.tag[:]) != tcase.publicTag {
+			t.Fatalf("incorrect tags %s %s", private.tag[:], public.tag[:])
+		}
+		testPrivateECKey(private, t)
+		testPublicECKey(public, t)
+		// key copied
+		key.D.SetInt64(1)
+		key.X.SetInt64(1)
+		if private.private.D.Cmp(big.NewInt(1)) == 0 || public.x.Cmp(big.NewInt(1)) == 0 {
+			t.Fatal("key not copied")
+		}
+
+		smessage, err := NewSecureMessage(private, public)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data := []byte("some data")
+		wrapped, err := smessage.Wrap(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		unwrapped, err := smessage.Unwrap(wrapped)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(unwrapped, data) {
+			t.Fatal("decrypted data not equal to source data")
+		}
+	}
+}
+
+func TestFromECDSAKeysInvalid(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p224, err := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := FromECDSAPrivateKey(p224); err != ErrUnsupportedCurve {
+		t.Fatal("expected ErrUnsupportedCurve", err)
+	}
+	if _, err := FromECDSAPublicKey(&p224.PublicKey); err != ErrUnsupportedCurve {
+		t.Fatal("expected ErrUnsupportedCurve", err)
+	}
+	mismatched := &ecdsa.PrivateKey{PublicKey: other.PublicKey, D: key.D}
+	zero := &ecdsa.PrivateKey{PublicKey: ecdsa.PublicKey{Curve: elliptic.P256()}, D: new(big.Int)}
+	order := &ecdsa.PrivateKey{PublicKey: ecdsa.PublicKey{Curve: elliptic.P256()}, D: elliptic.P256().Params().N}
+	for _, private := range []*ecdsa.PrivateKey{nil, mismatched, zero, order} {
+		if _, err := FromECDSAPrivateKey(private); err != ErrInvalidPrivateKey {
+			t.Fatal("expected ErrInvalidPrivateKey", err)
+		}
+	}
+	notOnCurve := &ecdsa.PublicKey{Curve: elliptic.P256(), X: key.X, Y: new(big.Int).Add(key.Y, big.NewInt(1))}
+	for _, public := range []*ecdsa.PublicKey{nil, {Curve: elliptic.P256()}, notOnCurve} {
+		if _, err := FromECDSAPublicKey(public); err != ErrInvalidPublicKey {
+			t.Fatal("expected ErrInvalidPublicKey", err)
+		}
+	}
+}
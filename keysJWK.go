@@ -0,0 +1,264 @@
+package gothemis
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+)
+
+// jwkKeyTypeEC value of "kty" member for EC keys
+const jwkKeyTypeEC = "EC"
+
+// jsonWebKey EC key in RFC 7517 format. Coordinates and private key encoded with base64url without padding
+type jsonWebKey struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid,omitempty"`
+	Curve   string `json:"crv,omitempty"`
+	X       string `json:"x,omitempty"`
+	Y       string `json:"y,omitempty"`
+	D       string `json:"d,omitempty"`
+}
+
+// jsonWebKeySet set of keys in RFC 7517 format. Keys stored raw to skip keys with unsupported types
+type jsonWebKeySet struct {
+	Keys []json.RawMessage `json:"keys"`
+}
+
+var ErrInvalidJWK = errors.New("incorrect JSON Web Key")
+
+// ErrPrivateKeyJSON returned by MarshalJSON of private key to not export private key implicitly with encoding/json
+var ErrPrivateKeyJSON = errors.New("private key can't be marshalled to JSON implicitly, use MarshalPrivateJWK")
+
+var jwkCurves = map[string]elliptic.Curve{
+	elliptic.P256().Params().Name: elliptic.P256(),
+	elliptic.P384().Params().Name: elliptic.P384(),
+	elliptic.P521().Params().Name: elliptic.P521(),
+}
+
+func encodeJWKInt(value *big.Int, size int) string {
+	return base64.RawURLEncoding.EncodeToString(alignPointInBytes(size, value))
+}
+
+// decodeJWKInt decode base64url value that must have exactly size bytes as RFC 7518 requires
+func decodeJWKInt(value string, size int) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.Strict().DecodeString(value)
+	if err != nil || len(data) != size {
+		return nil, ErrInvalidJWK
+	}
+	defer Zeroize(data)
+	return new(big.Int).SetBytes(data), nil
+}
+
+func newJSONWebKey(public *ecdsa.PublicKey) *jsonWebKey {
+	size := curveKeySize(public.Curve)
+	return &jsonWebKey{
+		KeyType: jwkKeyTypeEC,
+		Curve:   public.Curve.Params().Name,
+		X:       encodeJWKInt(public.X, size),
+		Y:       encodeJWKInt(public.Y, size),
+	}
+}
+
+// thumbprint return RFC 7638 thumbprint of key with SHA-256 encoded with base64url
+func (jwk *jsonWebKey) thumbprint() string {
+	// required members in lexicographic order without whitespaces
+	canonical := `{"crv":"` + jwk.Curve + `","kty":"` + jwk.KeyType + `","x":"` + jwk.X + `","y":"` + jwk.Y + `"}`
+	digest := sha256.Sum256([]byte(canonical))
+	return base64.RawURLEncoding.EncodeToString(digest[:])
+}
+
+// publicKey return validated public key from jwk
+func (jwk *jsonWebKey) publicKey() (*ecdsa.PublicKey, error) {
+	if jwk.KeyType != jwkKeyTypeEC {
+		return nil, ErrInvalidJWK
+	}
+	curve, ok := jwkCurves[jwk.Curve]
+	if !ok {
+		return nil, ErrUnsupportedCurve
+	}
+	size := curveKeySize(curve)
+	x, err := decodeJWKInt(jwk.X, size)
+	if err != nil {
+		return nil, err
+	}
+	y, err := decodeJWKInt(jwk.Y, size)
+	if err != nil {
+		return nil, err
+	}
+	if !curve.IsOnCurve(x, y) {
+		return nil, ErrInvalidJWK
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+// privateKey return validated private key from jwk
+func (jwk *jsonWebKey) privateKey() (*ecdsa.PrivateKey, error) {
+	public, err := jwk.publicKey()
+	if err != nil {
+		return nil, err
+	}
+	size := curveKeySize(public.Curve)
+	d, err := decodeJWKInt(jwk.D, size)
+	if err != nil {
+		return nil, err
+	}
+	scalar := alignPointInBytes(size, d)
+	defer Zeroize(scalar)
+	private, err := newPrivateECKeyFromBytes(public.Curve, scalar)
+	if err != nil {
+		return nil, ErrInvalidJWK
+	}
+	if private.X.Cmp(public.X) != 0 || private.Y.Cmp(public.Y) != 0 {
+		return nil, ErrInvalidJWK
+	}
+	return private, nil
+}
+
+// KeyID return RFC 7638 thumbprint of key used as "kid" in JWK
+func (key *PublicECKey) KeyID() string {
+	return newJSONWebKey(key.Public()).thumbprint()
+}
+
+// MarshalJSON return public key as JWK with "kid" set to KeyID
+func (key *PublicECKey) MarshalJSON() ([]byte, error) {
+	jwk := newJSONWebKey(key.Public())
+	jwk.KeyID = jwk.thumbprint()
+	return json.Marshal(jwk)
+}
+
+// UnmarshalJSON parse public key from JWK. "kid" member ignored
+func (key *PublicECKey) UnmarshalJSON(data []byte) error {
+	jwk := &jsonWebKey{}
+	if err := json.Unmarshal(data, jwk); err != nil {
+		return err
+	}
+	public, err := jwk.publicKey()
+	if err != nil {
+		return err
+	}
+	parsed, err := newPublicECKey(public)
+	if err != nil {
+		return err
+	}
+	*key = *parsed
+	return nil
+}
+
+// KeyID return RFC 7638 thumbprint of public part of key used as "kid" in JWK
+func (key *PrivateECKey) KeyID() string {
+	return newJSONWebKey(&key.private.PublicKey).thumbprint()
+}
+
+// MarshalJSON always returns ErrPrivateKeyJSON so private key doesn't leak as part of other marshalled value. Use
+// MarshalPrivateJWK to export private key explicitly
+func (key *PrivateECKey) MarshalJSON() ([]byte, error) {
+	return nil, ErrPrivateKeyJSON
+}
+
+// MarshalPrivateJWK return private key as JWK with "d" member and "kid" set to KeyID
+func (key *PrivateECKey) MarshalPrivateJWK() ([]byte, error) {
+	jwk := newJSONWebKey(&key.private.PublicKey)
+	jwk.KeyID = jwk.thumbprint()
+	jwk.D = encodeJWKInt(key.private.D, curveKeySize(key.private.Curve))
+	return json.Marshal(jwk)
+}
+
+// UnmarshalJSON parse private key from JWK. "kid" member ignored
+func (key *PrivateECKey) UnmarshalJSON(data []byte) error {
+	jwk := &jsonWebKey{}
+	if err := json.Unmarshal(data, jwk); err != nil {
+		return err
+	}
+	private, err := jwk.privateKey()
+	if err != nil {
+		return err
+	}
+	parsed, err := newPrivateECKey(private)
+	if err != nil {
+		return err
+	}
+	*key = *parsed
+	return nil
+}
+
+// PrivateJWK opt-in wrapper that marshals private key to JSON as JWK with "d" member. Use it instead of *PrivateECKey
+// in values that should export private key with json.Marshal
+type PrivateJWK struct {
+	*PrivateECKey
+}
+
+// MarshalJSON return wrapped private key as JWK or null if key is nil
+func (jwk PrivateJWK) MarshalJSON() ([]byte, error) {
+	if jwk.PrivateECKey == nil {
+		return []byte("null"), nil
+	}
+	return jwk.MarshalPrivateJWK()
+}
+
+// UnmarshalJSON parse private key from JWK into new PrivateECKey
+func (jwk *PrivateJWK) UnmarshalJSON(data []byte) error {
+	key := &PrivateECKey{}
+	if err := key.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	jwk.PrivateECKey = key
+	return nil
+}
+
+// MarshalJWKSet return public keys as RFC 7517 JWK set
+func MarshalJWKSet(keys ...*PublicECKey) ([]byte, error) {
+	set := jsonWebKeySet{Keys: make([]json.RawMessage, 0, len(keys))}
+	for _, key := range keys {
+		jwk, err := key.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return json.Marshal(set)
+}
+
+// UnmarshalJWKSet parse EC keys from RFC 7517 JWK set. Keys with other "kty" and keys on unsupported curves
+// skipped as RFC recommends. Both slices have same length: privateKeys[i] is private key for publicKeys[i] or nil
+// if JWK has no private part
+func UnmarshalJWKSet(data []byte) ([]*PublicECKey, []*PrivateECKey, error) {
+	set := jsonWebKeySet{}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, nil, err
+	}
+	if set.Keys == nil {
+		return nil, nil, ErrInvalidJWK
+	}
+	var publicKeys []*PublicECKey
+	var privateKeys []*PrivateECKey
+	for _, rawKey := range set.Keys {
+		jwk := &jsonWebKey{}
+		if err := json.Unmarshal(rawKey, jwk); err != nil {
+			return nil, nil, err
+		}
+		if jwk.KeyType != jwkKeyTypeEC {
+			continue
+		}
+		if _, ok := jwkCurves[jwk.Curve]; !ok {
+			continue
+		}
+		public := &PublicECKey{}
+		if err := public.UnmarshalJSON(rawKey); err != nil {
+			return nil, nil, err
+		}
+		var private *PrivateECKey
+		if jwk.D != "" {
+			private = &PrivateECKey{}
+			if err := private.UnmarshalJSON(rawKey); err != nil {
+				return nil, nil, err
+			}
+		}
+		publicKeys = append(publicKeys, public)
+		privateKeys = append(privateKeys, private)
+	}
+	return publicKeys, privateKeys, nil
+}
@@ -0,0 +1,194 @@
+package gothemis
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+)
+
+// rfc7517PrivateKey example EC private key from RFC 7517 appendix A.2
+const rfc7517PrivateKey = `{"kty":"EC","crv":"P-256","x":"MKBCTNIcKUSDii11ySs3526iDZ8AiTo7Tu6KPAqv7D4",` +
+	`"y":"4Etl6SRW2YiLUrN5vfvVHuhp7x8PxltmWWlbbM4IFyM","d":"870MB6gfuTJ4HtUnUvYMyJpr5eUZNP4Bk43bVdj3eAE","use":"enc","kid":"1"}`
+
+func TestECKeyJWK(t *testing.T) {
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		kp, err := NewECKeyPairWithCurve(curve)
+		if err != nil {
+			t.Fatal(err)
+		}
+		publicJSON, err := json.Marshal(kp.Public)
+		if err != nil {
+			t.Fatal(err)
+		}
+		public := &PublicECKey{}
+		if err := json.Unmarshal(publicJSON, public); err != nil {
+			t.Fatal(err)
+		}
+		testPublicKeysEqual(kp.Public, public, t)
+
+		// private key exported only explicitly
+		if _, err := json.Marshal(kp.Private); err == nil {
+			t.Fatal("expected error on implicit JSON marshalling of private key")
+		}
+		if _, err := json.Marshal(kp); err == nil {
+			t.Fatal("expected error on implicit JSON marshalling of private key")
+		}
+		privateJSON, err := kp.Private.MarshalPrivateJWK()
+		if err != nil {
+			t.Fatal(err)
+		}
+		private := &PrivateECKey{}
+		if err := json.Unmarshal(privateJSON, private); err != nil {
+			t.Fatal(err)
+		}
+		expected, err := kp.Private.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		encoded, err := private.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(expected, encoded) {
+			t.Fatal("private keys not equal")
+		}
+
+		var jwk map[string]string
+		if err := json.Unmarshal(privateJSON, &jwk); err != nil {
+			t.Fatal(err)
+		}
+		if jwk["crv"] != curve.Params().Name || jwk["kid"] != kp.Public.KeyID() || kp.Private.KeyID() != kp.Public.KeyID() {
+			t.Fatalf("incorrect JWK %v", jwk)
+		}
+		// private key can't be parsed from public JWK
+		if err := json.Unmarshal(publicJSON, private); err != ErrInvalidJWK {
+			t.Fatal("expected ErrInvalidJWK", err)
+		}
+	}
+}
+
+func TestPrivateJWK(t *testing.T) {
+	kp, err := NewECKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	type keys struct {
+		Public  *PublicECKey `json:"public"`
+		Private PrivateJWK   `json:"private"`
+	}
+	data, err := json.Marshal(keys{Public: kp.Public, Private: PrivateJWK{kp.Private}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed := keys{}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		t.Fatal(err)
+	}
+	testPublicKeysEqual(kp.Public, parsed.Public, t)
+	expected, err := kp.Private.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := parsed.Private.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(expected, encoded) {
+		t.Fatal("private keys not equal")
+	}
+	data, err = json.Marshal(PrivateJWK{})
+	if err != nil || string(data) != "null" {
+		t.Fatal("expected null for empty PrivateJWK", err)
+	}
+}
+
+func testPublicKeysEqual(expected, actual *PublicECKey, t *testing.T) {
+	expectedBytes, err := expected.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	actualBytes, err := actual.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(expectedBytes, actualBytes) {
+		t.Fatal("public keys not equal")
+	}
+}
+
+func TestECKeyJWKVector(t *testing.T) {
+	private := &PrivateECKey{}
+	if err := json.Unmarshal([]byte(rfc7517PrivateKey), private); err != nil {
+		t.Fatal(err)
+	}
+	canonical := `{"crv":"P-256","kty":"EC","x":"MKBCTNIcKUSDii11ySs3526iDZ8AiTo7Tu6KPAqv7D4","y":"4Etl6SRW2YiLUrN5vfvVHuhp7x8PxltmWWlbbM4IFyM"}`
+	digest := sha256.Sum256([]byte(canonical))
+	if private.KeyID() != base64.RawURLEncoding.EncodeToString(digest[:]) {
+		t.Fatal("incorrect thumbprint")
+	}
+
+	invalid := []string{
+		`{"kty":"EC","crv":"P-256","x":"MKBCTNIcKUSDii11ySs3526iDZ8AiTo7Tu6KPAqv7D4","y":"4Etl6SRW2YiLUrN5vfvVHuhp7x8PxltmWWlbbM4IFyN"}`,
+		`{"kty":"EC","crv":"P-256","x":"MKBCTNIcKUSDii11ySs3526iDZ8AiTo7Tu6KPAqv7D4","y":"4Etl6SRW2YjLUrN5vfvVHuhp7x8PxltmWWlbbM4IFyM"}`,
+		`{"kty":"EC","crv":"P-256","x":"MKBCTNIcKUSDii11ySs3526iDZ8AiTo7Tu6KPAqv7D4","y":"4Etl6SRW2YiLUrN5vfvVHuhp7x8PxltmWWlbbM4IF"}`,
+		`{"kty":"RSA","crv":"P-256","x":"MKBCTNIcKUSDii11ySs3526iDZ8AiTo7Tu6KPAqv7D4","y":"4Etl6SRW2YiLUrN5vfvVHuhp7x8PxltmWWlbbM4IFyM"}`,
+	}
+	for _, jwk := range invalid {
+		if err := json.Unmarshal([]byte(jwk), &PublicECKey{}); err != ErrInvalidJWK {
+			t.Fatal("expected ErrInvalidJWK", jwk, err)
+		}
+	}
+	if err := json.Unmarshal([]byte(`{"kty":"EC","crv":"P-224","x":"","y":""}`), &PublicECKey{}); err != ErrUnsupportedCurve {
+		t.Fatal("expected ErrUnsupportedCurve", err)
+	}
+	// private key must match public key
+	mismatched := `{"kty":"EC","crv":"P-256","x":"MKBCTNIcKUSDii11ySs3526iDZ8AiTo7Tu6KPAqv7D4",` +
+		`"y":"4Etl6SRW2YiLUrN5vfvVHuhp7x8PxltmWWlbbM4IFyM","d":"870MB6gfuTJ4HtUnUvYMyJpr5eUZNP4Bk43bVdj3eAA"}`
+	if err := json.Unmarshal([]byte(mismatched), &PrivateECKey{}); err != ErrInvalidJWK {
+		t.Fatal("expected ErrInvalidJWK", err)
+	}
+}
+
+func TestJWKSet(t *testing.T) {
+	kp256, err := NewECKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	kp521, err := NewECKeyPairWithCurve(elliptic.P521())
+	if err != nil {
+		t.Fatal(err)
+	}
+	set, err := MarshalJWKSet(kp256.Public, kp521.Public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKeys, privateKeys, err := UnmarshalJWKSet(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(publicKeys) != 2 || len(privateKeys) != 2 || privateKeys[0] != nil || privateKeys[1] != nil {
+		t.Fatal("incorrect keys in set")
+	}
+	testPublicKeysEqual(kp256.Public, publicKeys[0], t)
+	testPublicKeysEqual(kp521.Public, publicKeys[1], t)
+
+	// unknown key types skipped, private keys parsed
+	set = []byte(`{"keys":[{"kty":"oct","k":"AyM1SysPpbyDfgZld3umj1qzKObwVMkoqQ-EstJQLr_T-1qS0gZH75aKtMN3Yj0iPS4hcgUuTwjAzZr1Z9CAow"},` +
+		rfc7517PrivateKey + `]}`)
+	publicKeys, privateKeys, err = UnmarshalJWKSet(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(publicKeys) != 1 || len(privateKeys) != 1 || privateKeys[0] == nil {
+		t.Fatal("incorrect keys in set")
+	}
+	if publicKeys[0].KeyID() != privateKeys[0].KeyID() {
+		t.Fatal("private key doesn't match public key")
+	}
+	if _, _, err := UnmarshalJWKSet([]byte(`{}`)); err != ErrInvalidJWK {
+		t.Fatal("expected ErrInvalidJWK", err)
+	}
+}
@@ -0,0 +1,113 @@
+package gothemis
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+)
+
+// PEM block types used for EC keys
+const (
+	PEMPrivateKeyType   = "PRIVATE KEY"
+	PEMECPrivateKeyType = "EC PRIVATE KEY"
+	PEMPublicKeyType    = "PUBLIC KEY"
+)
+
+var ErrInvalidPEM = errors.New("incorrect PEM data")
+var ErrNotECKey = errors.New("key is not EC key")
+
+// MarshalPKCS8 return private key in PKCS#8 DER encoding
+func (key *PrivateECKey) MarshalPKCS8() ([]byte, error) {
+	return x509.MarshalPKCS8PrivateKey(key.private)
+}
+
+// MarshalSEC1 return private key in SEC 1 (RFC 5915) DER encoding
+func (key *PrivateECKey) MarshalSEC1() ([]byte, error) {
+	return x509.MarshalECPrivateKey(key.private)
+}
+
+// MarshalPEM return private key as PKCS#8 in PEM block with PEMPrivateKeyType
+func (key *PrivateECKey) MarshalPEM() ([]byte, error) {
+	der, err := key.MarshalPKCS8()
+	if err != nil {
+		return nil, err
+	}
+	defer Zeroize(der)
+	return pem.EncodeToMemory(&pem.Block{Type: PEMPrivateKeyType, Bytes: der}), nil
+}
+
+// MarshalPKIX return public key as SubjectPublicKeyInfo in DER encoding
+func (key *PublicECKey) MarshalPKIX() ([]byte, error) {
+	return x509.MarshalPKIXPublicKey(key.Public())
+}
+
+// MarshalPEM return public key as SubjectPublicKeyInfo in PEM block with PEMPublicKeyType
+func (key *PublicECKey) MarshalPEM() ([]byte, error) {
+	der, err := key.MarshalPKIX()
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: PEMPublicKeyType, Bytes: der}), nil
+}
+
+// UnmarshalPKCS8ECPrivateKey parse EC private key from PKCS#8 DER encoding
+func UnmarshalPKCS8ECPrivateKey(der []byte) (*PrivateECKey, error) {
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+	private, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, ErrNotECKey
+	}
+	return newPrivateECKey(private)
+}
+
+// UnmarshalSEC1ECPrivateKey parse EC private key from SEC 1 DER encoding
+func UnmarshalSEC1ECPrivateKey(der []byte) (*PrivateECKey, error) {
+	private, err := x509.ParseECPrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+	return newPrivateECKey(private)
+}
+
+// UnmarshalPKIXECPublicKey parse EC public key from SubjectPublicKeyInfo DER encoding
+func UnmarshalPKIXECPublicKey(der []byte) (*PublicECKey, error) {
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, err
+	}
+	public, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, ErrNotECKey
+	}
+	return newPublicECKey(public)
+}
+
+// UnmarshalPEMECPrivateKey parse EC private key from PEM block with PKCS#8 (PEMPrivateKeyType) or SEC 1
+// (PEMECPrivateKeyType) data
+func UnmarshalPEMECPrivateKey(data []byte) (*PrivateECKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrInvalidPEM
+	}
+	defer Zeroize(block.Bytes)
+	switch block.Type {
+	case PEMPrivateKeyType:
+		return UnmarshalPKCS8ECPrivateKey(block.Bytes)
+	case PEMECPrivateKeyType:
+		return UnmarshalSEC1ECPrivateKey(block.Bytes)
+	}
+	return nil, ErrInvalidPEM
+}
+
+// UnmarshalPEMECPublicKey parse EC public key from PEM block with PEMPublicKeyType
+func UnmarshalPEMECPublicKey(data []byte) (*PublicECKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != PEMPublicKeyType {
+		return nil, ErrInvalidPEM
+	}
+	return UnmarshalPKIXECPublicKey(block.Bytes)
+}
@@ -0,0 +1,123 @@
+package gothemis
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"crypto/x509"
+	"testing"
+
+	"github.com/cossacklabs/themis/gothemis/keys"
+)
+
+func TestECKeyPEM(t *testing.T) {
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		kp, err := NewECKeyPairWithCurve(curve)
+		if err != nil {
+			t.Fatal(err)
+		}
+		themisPrivate, err := kp.Private.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		themisPublic, err := kp.Public.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		pkcs8, err := kp.Private.MarshalPKCS8()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sec1, err := kp.Private.MarshalSEC1()
+		if err != nil {
+			t.Fatal(err)
+		}
+		privatePEM, err := kp.Private.MarshalPEM()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, parse := range []func() (*PrivateECKey, error){
+			func() (*PrivateECKey, error) { return UnmarshalPKCS8ECPrivateKey(pkcs8) },
+			func() (*PrivateECKey, error) { return UnmarshalSEC1ECPrivateKey(sec1) },
+			func() (*PrivateECKey, error) { return UnmarshalPEMECPrivateKey(privatePEM) },
+		} {
+			private, err := parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+			encoded, err := private.Marshal()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(encoded, themisPrivate) {
+				t.Fatal("private keys not equal")
+			}
+		}
+
+		publicPEM, err := kp.Public.MarshalPEM()
+		if err != nil {
+			t.Fatal(err)
+		}
+		public, err := UnmarshalPEMECPublicKey(publicPEM)
+		if err != nil {
+			t.Fatal(err)
+		}
+		encoded, err := public.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(encoded, themisPublic) {
+			t.Fatal("public keys not equal")
+		}
+
+		if _, err := UnmarshalPEMECPublicKey(privatePEM); err != ErrInvalidPEM {
+			t.Fatal("expected ErrInvalidPEM", err)
+		}
+		if _, err := UnmarshalPEMECPrivateKey(publicPEM); err != ErrInvalidPEM {
+			t.Fatal("expected ErrInvalidPEM", err)
+		}
+	}
+	if _, err := UnmarshalPEMECPrivateKey([]byte("some data")); err != ErrInvalidPEM {
+		t.Fatal("expected ErrInvalidPEM", err)
+	}
+}
+
+func TestECKeyPEMNotEC(t *testing.T) {
+	kp, err := NewRSAKeyPairWithSize(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(kp.Private.private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := UnmarshalPKCS8ECPrivateKey(der); err != ErrNotECKey {
+		t.Fatal("expected ErrNotECKey", err)
+	}
+}
+
+func TestThemisECKeyPEM(t *testing.T) {
+	keypair, err := keys.New(keys.TypeEC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	private, err := UnmarshalThemisECPrivateKey(keypair.Private.Value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privatePEM, err := private.MarshalPEM()
+	if err != nil {
+		t.Fatal(err)
+	}
+	private, err = UnmarshalPEMECPrivateKey(privatePEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := private.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encoded, keypair.Private.Value) {
+		t.Fatal("private keys not equal")
+	}
+}
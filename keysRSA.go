@@ -0,0 +1,302 @@
+package gothemis
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/big"
+
+	"github.com/cossacklabs/themis/gothemis/keys"
+)
+
+var (
+	rsaPrivateKeyPrefix = []byte("RRA")
+	rsaPublicKeyPrefix  = []byte("URA")
+)
+
+// DefaultRSAKeySize size of RSA keys generated by Themis
+const DefaultRSAKeySize = 2048
+
+var sizeSuffixToRSAKeySize = map[byte]int{
+	'1': 1024,
+	'2': 2048,
+	'4': 4096,
+	'8': 8192,
+}
+
+var rsaKeySizeToSizeSuffix = map[int]byte{
+	1024: '1',
+	2048: '2',
+	4096: '4',
+	8192: '8',
+}
+
+// rsaPublicExponentSize public exponent stored as big endian uint32 after header
+const rsaPublicExponentSize = 4
+
+var ErrUnsupportedRSAKeySize = errors.New("unsupported RSA key size")
+var ErrInvalidRSAKey = errors.New("incorrect RSA key")
+
+// PublicRSAKey RSA public key stored in soter container:
+// header, public exponent[uint32] and modulus
+type PublicRSAKey struct {
+	tag    [ecKeyTagLength]byte
+	size   int32
+	public *rsa.PublicKey
+}
+
+// PrivateRSAKey RSA private key stored in soter container:
+// header, public exponent[uint32], private exponent, p, q, dp, dq, qp and modulus. Private exponent and modulus have
+// length of key, other parts - half of key length
+type PrivateRSAKey struct {
+	tag     [ecKeyTagLength]byte
+	size    int32
+	private *rsa.PrivateKey
+}
+
+// rsaPublicKeySize return length of public key container with modulus of modulusSize bytes
+func rsaPublicKeySize(modulusSize int) int {
+	return ecKeyHeaderSize + rsaPublicExponentSize + modulusSize
+}
+
+// rsaPrivateKeySize return length of private key container with modulus of modulusSize bytes
+func rsaPrivateKeySize(modulusSize int) int {
+	return ecKeyHeaderSize + rsaPublicExponentSize + 2*modulusSize + 5*(modulusSize/2)
+}
+
+func rsaKeyTag(prefix []byte, bits int) ([ecKeyTagLength]byte, error) {
+	var tag [ecKeyTagLength]byte
+	suffix, ok := rsaKeySizeToSizeSuffix[bits]
+	if !ok {
+		return tag, ErrUnsupportedRSAKeySize
+	}
+	copy(tag[:], prefix)
+	tag[ecKeyTagLength-1] = suffix
+	return tag, nil
+}
+
+// marshalRSAKeyContainer fill header of container with tag and size and calculate crc
+func marshalRSAKeyContainer(output []byte, tag [ecKeyTagLength]byte) error {
+	container := soterContainer(output)
+	if err := container.setTag(tag[:]); err != nil {
+		return err
+	}
+	if err := container.setSizeToContainer(len(output) - containerLength); err != nil {
+		return err
+	}
+	return container.calculateCRC(output)
+}
+
+func (key *PublicRSAKey) Public() *rsa.PublicKey {
+	return key.public
+}
+
+func (key *PublicRSAKey) Marshal() ([]byte, error) {
+	modulusSize := key.public.Size()
+	output := make([]byte, rsaPublicKeySize(modulusSize))
+	binary.BigEndian.PutUint32(output[ecKeyHeaderSize:ecKeyHeaderSize+rsaPublicExponentSize], uint32(key.public.E))
+	key.public.N.FillBytes(output[ecKeyHeaderSize+rsaPublicExponentSize:])
+	if err := marshalRSAKeyContainer(output, key.tag); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+// Public return copy of *rsa.PublicKey of key so caller can't change key through it. Implements crypto.Signer
+func (key *PrivateRSAKey) Public() crypto.PublicKey {
+	return &rsa.PublicKey{N: new(big.Int).Set(key.private.N), E: key.private.E}
+}
+
+// Sign digest with RSA key. opts should be *rsa.PSSOptions for RSA-PSS signatures that Themis uses, otherwise
+// PKCS #1 v1.5 used. Implements crypto.Signer
+func (key *PrivateRSAKey) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return key.private.Sign(rand, digest, opts)
+}
+
+// Zeroize wipes private exponent, primes and exported precomputed values including CRTValues. Precompute also stores
+// unexported copies of primes and values derived from them in rsa.PrivateKey (moduli since Go 1.20, whole FIPS key
+// since Go 1.24) that are out of reach, so they stay in memory until collected
+func (key *PrivateRSAKey) Zeroize() {
+	zeroizeBigInt(key.private.D)
+	for _, prime := range key.private.Primes {
+		zeroizeBigInt(prime)
+	}
+	zeroizeBigInt(key.private.Precomputed.Dp)
+	zeroizeBigInt(key.private.Precomputed.Dq)
+	zeroizeBigInt(key.private.Precomputed.Qinv)
+	for _, value := range key.private.Precomputed.CRTValues {
+		zeroizeBigInt(value.Exp)
+		zeroizeBigInt(value.Coeff)
+		zeroizeBigInt(value.R)
+	}
+}
+
+func (key *PrivateRSAKey) Marshal() ([]byte, error) {
+	modulusSize := key.private.Size()
+	primeSize := modulusSize / 2
+	output := make([]byte, rsaPrivateKeySize(modulusSize))
+	binary.BigEndian.PutUint32(output[ecKeyHeaderSize:ecKeyHeaderSize+rsaPublicExponentSize], uint32(key.private.E))
+	offset := ecKeyHeaderSize + rsaPublicExponentSize
+	parts := []struct {
+		value *big.Int
+		size  int
+	}{
+		{key.private.D, modulusSize},
+		{key.private.Primes[0], primeSize},
+		{key.private.Primes[1], primeSize},
+		{key.private.Precomputed.Dp, primeSize},
+		{key.private.Precomputed.Dq, primeSize},
+		{key.private.Precomputed.Qinv, primeSize},
+		{key.private.N, modulusSize},
+	}
+	for _, part := range parts {
+		part.value.FillBytes(output[offset : offset+part.size])
+		offset += part.size
+	}
+	if err := marshalRSAKeyContainer(output, key.tag); err != nil {
+		Zeroize(output)
+		return nil, err
+	}
+	return output, nil
+}
+
+func newPrivateRSAKey(privateKey *rsa.PrivateKey) (*PrivateRSAKey, error) {
+	if len(privateKey.Primes) != 2 {
+		return nil, ErrInvalidRSAKey
+	}
+	tag, err := rsaKeyTag(rsaPrivateKeyPrefix, privateKey.N.BitLen())
+	if err != nil {
+		return nil, err
+	}
+	privateKey.Precompute()
+	return &PrivateRSAKey{tag: tag, size: int32(rsaPrivateKeySize(privateKey.Size())), private: privateKey}, nil
+}
+
+func newPublicRSAKey(publicKey *rsa.PublicKey) (*PublicRSAKey, error) {
+	tag, err := rsaKeyTag(rsaPublicKeyPrefix, publicKey.N.BitLen())
+	if err != nil {
+		return nil, err
+	}
+	return &PublicRSAKey{tag: tag, size: int32(rsaPublicKeySize(publicKey.Size())), public: publicKey}, nil
+}
+
+type RSAKeyPair struct {
+	Private *PrivateRSAKey
+	Public  *PublicRSAKey
+}
+
+func (k *RSAKeyPair) ToThemisKeyPair() (*keys.Keypair, error) {
+	privateKey, err := k.Private.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := k.Public.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	return &keys.Keypair{
+		Private: &keys.PrivateKey{Value: privateKey},
+		Public:  &keys.PublicKey{Value: publicKey}}, nil
+}
+
+// NewRSAKeyPair generate RSA key pair with DefaultRSAKeySize like Themis does
+func NewRSAKeyPair() (*RSAKeyPair, error) {
+	return NewRSAKeyPairWithSize(DefaultRSAKeySize)
+}
+
+// NewRSAKeyPairWithSize generate RSA key pair with 1024, 2048, 4096 or 8192 bits modulus
+func NewRSAKeyPairWithSize(bits int) (*RSAKeyPair, error) {
+	if _, ok := rsaKeySizeToSizeSuffix[bits]; !ok {
+		return nil, ErrUnsupportedRSAKeySize
+	}
+	privateKey, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		return nil, err
+	}
+	private, err := newPrivateRSAKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	public, err := newPublicRSAKey(&privateKey.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return &RSAKeyPair{Private: private, Public: public}, nil
+}
+
+// unmarshalRSAKeyHeader validate container with prefix and return tag, size and length of modulus in bytes
+func unmarshalRSAKeyHeader(rawKey []byte, prefix []byte) ([]byte, int32, int, error) {
+	if len(rawKey) < ecKeyHeaderSize {
+		return nil, 0, 0, ErrInvalidKeyDataLength
+	}
+	if !bytes.Equal(rawKey[:ecKeyTagLength-1], prefix) {
+		return nil, 0, 0, ErrInvalidKeyTag
+	}
+	bits, ok := sizeSuffixToRSAKeySize[rawKey[ecKeyTagLength-1]]
+	if !ok {
+		return nil, 0, 0, ErrInvalidKeyTag
+	}
+	tag, size, err := unmarshalKeyContainer(rawKey)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return tag, size, bits / 8, nil
+}
+
+func UnmarshalThemisRSAPublicKey(rawKey []byte) (*PublicRSAKey, error) {
+	tag, size, modulusSize, err := unmarshalRSAKeyHeader(rawKey, rsaPublicKeyPrefix)
+	if err != nil {
+		return nil, err
+	}
+	if len(rawKey) != rsaPublicKeySize(modulusSize) {
+		return nil, ErrInvalidKeyDataLength
+	}
+	data := rawKey[ecKeyHeaderSize:]
+	public := &rsa.PublicKey{
+		E: int(binary.BigEndian.Uint32(data[:rsaPublicExponentSize])),
+		N: new(big.Int).SetBytes(data[rsaPublicExponentSize:]),
+	}
+	if public.E < 3 || public.E&1 == 0 || public.N.BitLen() != modulusSize*8 {
+		return nil, ErrInvalidRSAKey
+	}
+	key := &PublicRSAKey{size: size, public: public}
+	copy(key.tag[:], tag)
+	return key, nil
+}
+
+func UnmarshalThemisRSAPrivateKey(rawKey []byte) (*PrivateRSAKey, error) {
+	tag, size, modulusSize, err := unmarshalRSAKeyHeader(rawKey, rsaPrivateKeyPrefix)
+	if err != nil {
+		return nil, err
+	}
+	if len(rawKey) != rsaPrivateKeySize(modulusSize) {
+		return nil, ErrInvalidKeyDataLength
+	}
+	primeSize := modulusSize / 2
+	data := rawKey[ecKeyHeaderSize:]
+	exponent := int(binary.BigEndian.Uint32(data[:rsaPublicExponentSize]))
+	data = data[rsaPublicExponentSize:]
+	d := new(big.Int).SetBytes(data[:modulusSize])
+	data = data[modulusSize:]
+	p := new(big.Int).SetBytes(data[:primeSize])
+	q := new(big.Int).SetBytes(data[primeSize : 2*primeSize])
+	// dp, dq and qp recalculated by Precompute
+	data = data[5*primeSize:]
+	n := new(big.Int).SetBytes(data)
+	private := &rsa.PrivateKey{
+		PublicKey: rsa.PublicKey{N: n, E: exponent},
+		D:         d,
+		Primes:    []*big.Int{p, q},
+	}
+	if err := private.Validate(); err != nil {
+		return nil, ErrInvalidRSAKey
+	}
+	private.Precompute()
+	key := &PrivateRSAKey{size: size, private: private}
+	copy(key.tag[:], tag)
+	return key, nil
+}
@@ -0,0 +1,129 @@
+package gothemis
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"math/big"
+	"testing"
+
+	"github.com/cossacklabs/themis/gothemis/keys"
+)
+
+func TestNewRSAKeyPair(t *testing.T) {
+	for _, bits := range []int{1024, 2048} {
+		kp, err := NewRSAKeyPairWithSize(bits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		privateBytes, err := kp.Private.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		publicBytes, err := kp.Public.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(privateBytes) != int(kp.Private.size) || len(publicBytes) != int(kp.Public.size) {
+			t.Fatal("incorrect size of marshaled keys")
+		}
+		private, err := UnmarshalThemisRSAPrivateKey(privateBytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !private.private.Equal(kp.Private.private) {
+			t.Fatal("private keys not equal")
+		}
+		public, err := UnmarshalThemisRSAPublicKey(publicBytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !public.public.Equal(kp.Public.public) {
+			t.Fatal("public keys not equal")
+		}
+		// crypto.Signer returns copy of public key
+		signerPublic := kp.Private.Public().(*rsa.PublicKey)
+		if !signerPublic.Equal(kp.Public.public) {
+			t.Fatal("incorrect public key of signer")
+		}
+		signerPublic.N.SetInt64(1)
+		if !kp.Private.Public().(*rsa.PublicKey).Equal(kp.Public.public) {
+			t.Fatal("public key of signer changed through Public")
+		}
+		if _, err := UnmarshalThemisRSAPublicKey(privateBytes); err != ErrInvalidKeyTag {
+			t.Fatal("expected ErrInvalidKeyTag", err)
+		}
+		privateBytes[len(privateBytes)-1] ^= 1
+		if _, err := UnmarshalThemisRSAPrivateKey(privateBytes); err != ErrInvalidCrc32Check {
+			t.Fatal("expected ErrInvalidCrc32Check", err)
+		}
+	}
+	if _, err := NewRSAKeyPairWithSize(3072); err != ErrUnsupportedRSAKeySize {
+		t.Fatal("expected ErrUnsupportedRSAKeySize")
+	}
+}
+
+func TestUnmarshalThemisRSAKeys(t *testing.T) {
+	keypair, err := keys.New(keys.TypeRSA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	private, err := UnmarshalThemisRSAPrivateKey(keypair.Private.Value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := private.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encoded, keypair.Private.Value) {
+		t.Fatalf("Keys not equal:\nexpect: \n%v\ntook:\n%v\n", keypair.Private.Value, encoded)
+	}
+	public, err := UnmarshalThemisRSAPublicKey(keypair.Public.Value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err = public.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encoded, keypair.Public.Value) {
+		t.Fatalf("Keys not equal:\nexpect: \n%v\ntook:\n%v\n", keypair.Public.Value, encoded)
+	}
+
+	kp, err := NewRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	themisKeypair, err := kp.ToThemisKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(themisKeypair.Private.Value[:ecKeyTagLength], []byte("RRA2")) ||
+		!bytes.Equal(themisKeypair.Public.Value[:ecKeyTagLength], []byte("URA2")) {
+		t.Fatal("incorrect key tags")
+	}
+}
+
+func TestPrivateRSAKeyZeroize(t *testing.T) {
+	// Themis keys have two primes, multi-prime key used to check CRTValues too
+	private, err := rsa.GenerateMultiPrimeKey(rand.Reader, 3, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(private.Precomputed.CRTValues) == 0 {
+		t.Fatal("expected CRT values for multi-prime key")
+	}
+	key := &PrivateRSAKey{private: private}
+	key.Zeroize()
+	values := []*big.Int{private.D, private.Precomputed.Dp, private.Precomputed.Dq, private.Precomputed.Qinv}
+	values = append(values, private.Primes...)
+	for _, value := range private.Precomputed.CRTValues {
+		values = append(values, value.Exp, value.Coeff, value.R)
+	}
+	for i, value := range values {
+		if value.Sign() != 0 {
+			t.Fatalf("value %d not wiped", i)
+		}
+	}
+}
@@ -2,9 +2,12 @@ package gothemis
 
 import (
 	"bytes"
+	"crypto/elliptic"
+	"encoding/binary"
 	"testing"
 
 	"github.com/cossacklabs/themis/gothemis/keys"
+	"github.com/cossacklabs/themis/gothemis/message"
 )
 
 func testPublicECKey(publicKey *PublicECKey, t *testing.T) {
@@ -58,6 +61,178 @@ func TestNewECKeyPair(t *testing.T) {
 	testPrivateECKey(kp.Private, t)
 }
 
+func TestNewECKeyPairWithCurve(t *testing.T) {
+	testCases := []struct {
+		curve      elliptic.Curve
+		publicTag  string
+		privateTag string
+		// compressed public key and private key with leading zero byte have same size
+		size int
+	}{
+		{elliptic.P256(), "UEC2", "REC2", ecKeyHeaderSize + 33},
+		{elliptic.P384(), "UEC3", "REC3", ecKeyHeaderSize + 49},
+		{elliptic.P521(), "UEC5", "REC5", ecKeyHeaderSize + 67},
+	}
+	for _, tcase := range testCases {
+		kp, err := NewECKeyPairWithCurve(tcase.curve)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testPublicECKey(kp.Public, t)
+		testPrivateECKey(kp.Private, t)
+		publicBytes, err := kp.Public.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		privateBytes, err := kp.Private.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(publicBytes[:ecKeyTagLength]) != tcase.publicTag || string(privateBytes[:ecKeyTagLength]) != tcase.privateTag {
+			t.Fatalf("incorrect tags %s %s", publicBytes[:ecKeyTagLength], privateBytes[:ecKeyTagLength])
+		}
+		if len(publicBytes) != tcase.size || len(privateBytes) != tcase.size {
+			t.Fatalf("incorrect key sizes %d %d, expected %d", len(publicBytes), len(privateBytes), tcase.size)
+		}
+		if TagToCurve(kp.Public.tag[:]) != tcase.curve {
+			t.Fatal("incorrect curve of public key")
+		}
+		// truncated key data with recalculated header
+		truncated := append([]byte{}, privateBytes[:len(privateBytes)-1]...)
+		binary.BigEndian.PutUint32(truncated[ecKeyTagLength:ecKeyTagLength+4], uint32(len(truncated)))
+		copy(truncated[ecKeyTagLength+4:ecKeyHeaderSize], []byte{0, 0, 0, 0})
+		h := NewCRC32()
+		h.Write(truncated)
+		binary.LittleEndian.PutUint32(truncated[ecKeyTagLength+4:ecKeyHeaderSize], h.Sum32())
+		if _, err := UnmarshalThemisECPrivateKey(truncated); err != ErrInvalidKeyDataLength {
+			t.Fatal("expected ErrInvalidKeyDataLength", err)
+		}
+	}
+	if _, err := NewECKeyPairWithCurve(elliptic.P224()); err != ErrUnsupportedCurve {
+		t.Fatal("expected ErrUnsupportedCurve")
+	}
+}
+
+func TestNewPrivateECKeyFromBytes(t *testing.T) {
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		kp, err := NewECKeyPairWithCurve(curve)
+		if err != nil {
+			t.Fatal(err)
+		}
+		size := curveKeySize(curve)
+		// private key container stores d with leading zero byte
+		d := append([]byte{0}, alignPointInBytes(size, kp.Private.private.D)...)
+		private, err := newPrivateECKeyFromBytes(curve, d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if private.X.Cmp(kp.Public.x) != 0 || private.Y.Cmp(kp.Public.y) != 0 || private.D.Cmp(kp.Private.private.D) != 0 {
+			t.Fatal("incorrect private key from bytes")
+		}
+		nonZeroPrefix := append([]byte{1}, d[1:]...)
+		order := alignPointInBytes(size, curve.Params().N)
+		for _, invalid := range [][]byte{nil, d[:size-1], nonZeroPrefix, make([]byte, size+1), order} {
+			if _, err := newPrivateECKeyFromBytes(curve, invalid); err != ErrInvalidPrivateKey {
+				t.Fatal("expected ErrInvalidPrivateKey", err)
+			}
+		}
+	}
+}
+
+func TestMarshalUncompressed(t *testing.T) {
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		kp, err := NewECKeyPairWithCurve(curve)
+		if err != nil {
+			t.Fatal(err)
+		}
+		compressed, err := kp.Public.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		uncompressed, err := kp.Public.MarshalUncompressed()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(uncompressed) != ecKeyHeaderSize+2*curveKeySize(curve)+1 || uncompressed[ecKeyHeaderSize] != 4 {
+			t.Fatal("incorrect uncompressed point")
+		}
+		if !bytes.Equal(uncompressed[:ecKeyTagLength], compressed[:ecKeyTagLength]) {
+			t.Fatal("tag depends on point encoding")
+		}
+		// checks size and CRC
+		if _, _, err := unmarshalKeyContainer(uncompressed); err != nil {
+			t.Fatal(err)
+		}
+		parsed, err := UnmarshalThemisECPublicKey(uncompressed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parsed.x.Cmp(kp.Public.x) != 0 || parsed.y.Cmp(kp.Public.y) != 0 {
+			t.Fatal("parsed key not equal to source key")
+		}
+		recompressed, err := parsed.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(recompressed, compressed) {
+			t.Fatal("compressed form of parsed key not equal to source")
+		}
+	}
+}
+
+func TestThemisUncompressedPublicKey(t *testing.T) {
+	alice, err := NewECKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, err := NewECKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	alicePrivate, err := alice.Private.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressed, err := bob.Public.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	uncompressed, err := bob.Public.MarshalUncompressed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bobSM, err := NewSecureMessage(bob.Private, alice.Public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte("some data")
+	signed, err := Sign(data, bob.Private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, bobPublic := range [][]byte{compressed, uncompressed} {
+		themisSM := message.New(&keys.PrivateKey{Value: alicePrivate}, &keys.PublicKey{Value: bobPublic})
+		wrapped, err := themisSM.Wrap(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		unwrapped, err := bobSM.Unwrap(wrapped)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(unwrapped, data) {
+			t.Fatal("decrypted data not equal to source data")
+		}
+		verified, err := message.New(nil, &keys.PublicKey{Value: bobPublic}).Verify(signed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(verified, data) {
+			t.Fatal("verified data not equal to source data")
+		}
+	}
+}
+
 func BenchmarkNewECKeyPair(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		kp, err := NewECKeyPair()
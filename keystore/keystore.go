@@ -0,0 +1,450 @@
+// Package keystore stores Themis keys of clients on filesystem with numbered versions.
+//
+// Keys of each client stored in own directory:
+//
+//	<dir>/<client id>/ec/<version>.priv
+//	<dir>/<client id>/ec/<version>.pub
+//	<dir>/<client id>/sym/<version>.key
+//
+// Private and symmetric keys optionally encrypted with master key by Secure Cell in Seal mode with relative path of
+// file as context, so encrypted key can't be moved to other client or version.
+package keystore
+
+import (
+	"crypto/rand"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lagovas/gothemis"
+)
+
+const (
+	ecKeysDirectory        = "ec"
+	symmetricKeysDirectory = "sym"
+
+	privateKeyExtension   = ".priv"
+	publicKeyExtension    = ".pub"
+	symmetricKeyExtension = ".key"
+
+	privateKeyPerm = 0600
+	publicKeyPerm  = 0644
+	directoryPerm  = 0700
+
+	// addVersionAttempts limits retries of addVersion when concurrent writers take next version
+	addVersionAttempts = 100
+)
+
+// Errors returned by FilesystemKeyStore
+var (
+	ErrInvalidClientID  = errors.New("client id must have 1-256 latin letters, digits, '-' or '_'")
+	ErrKeyNotFound      = errors.New("key not found")
+	ErrKeyVersionExists = errors.New("key with same version already exists")
+)
+
+var validClientID = regexp.MustCompile(`^[a-zA-Z0-9_\-]{1,256}$`)
+
+// FilesystemKeyStore stores keys in directory. Implements gothemis.PrivateKeySource
+type FilesystemKeyStore struct {
+	dir string
+	// masterKey encrypts private and symmetric keys, nil if keys stored unencrypted
+	masterKey *gothemis.SecureCellSeal
+}
+
+// NewFilesystemKeyStore return keystore that stores keys unencrypted in dir. Creates dir if it doesn't exist
+func NewFilesystemKeyStore(dir string) (*FilesystemKeyStore, error) {
+	if err := os.MkdirAll(dir, directoryPerm); err != nil {
+		return nil, err
+	}
+	return &FilesystemKeyStore{dir: dir}, nil
+}
+
+// NewEncryptedFilesystemKeyStore return keystore that stores keys in dir and encrypts private and symmetric keys
+// with masterKey
+func NewEncryptedFilesystemKeyStore(dir string, masterKey []byte) (*FilesystemKeyStore, error) {
+	cell, err := gothemis.SealWithKey(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	store, err := NewFilesystemKeyStore(dir)
+	if err != nil {
+		return nil, err
+	}
+	store.masterKey = cell
+	return store, nil
+}
+
+// relativeKeyPath return path of key file relative to store's directory
+func relativeKeyPath(clientID []byte, keysDirectory string, version uint32, extension string) string {
+	return filepath.Join(string(clientID), keysDirectory, formatVersion(version)+extension)
+}
+
+func formatVersion(version uint32) string {
+	return strconv.FormatUint(uint64(version), 10)
+}
+
+func (store *FilesystemKeyStore) keysDirectory(clientID []byte, keysDirectory string) (string, error) {
+	if !validClientID.Match(clientID) {
+		return "", ErrInvalidClientID
+	}
+	return filepath.Join(store.dir, string(clientID), keysDirectory), nil
+}
+
+// encrypt data with master key if it set. Path used as context
+func (store *FilesystemKeyStore) encrypt(data []byte, path string) ([]byte, error) {
+	if store.masterKey == nil {
+		return append([]byte{}, data...), nil
+	}
+	return store.masterKey.Encrypt(data, []byte(filepath.ToSlash(path)))
+}
+
+func (store *FilesystemKeyStore) decrypt(data []byte, path string) ([]byte, error) {
+	if store.masterKey == nil {
+		return data, nil
+	}
+	return store.masterKey.Decrypt(data, []byte(filepath.ToSlash(path)))
+}
+
+// writeFileAtomic writes data to temporary file and links it to path, so readers never see partially written keys.
+// Returns ErrKeyVersionExists if path already exists
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	// unlike rename, link fails if file exists so concurrent writers can't overwrite each other's keys
+	if err := os.Link(tmp.Name(), path); err != nil {
+		if os.IsExist(err) {
+			return ErrKeyVersionExists
+		}
+		return err
+	}
+	if dir, err := os.Open(filepath.Dir(path)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+	return nil
+}
+
+// listVersions return versions of keys with extensions in dir sorted from newest to oldest
+func listVersions(dir string, extensions ...string) ([]uint32, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var versions []uint32
+	for _, file := range files {
+		for _, extension := range extensions {
+			name := file.Name()
+			if !file.Type().IsRegular() || !strings.HasSuffix(name, extension) {
+				continue
+			}
+			version, err := strconv.ParseUint(strings.TrimSuffix(name, extension), 10, 32)
+			if err != nil || version == 0 {
+				continue
+			}
+			versions = append(versions, uint32(version))
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+	return versions, nil
+}
+
+// nextVersion return version after newest file with any extension in dir, including incomplete key pairs
+func nextVersion(dir string, extensions ...string) (uint32, error) {
+	versions, err := listVersions(dir, extensions...)
+	if err != nil {
+		return 0, err
+	}
+	if len(versions) == 0 {
+		return 1, nil
+	}
+	return versions[0] + 1, nil
+}
+
+// addVersion calls write with next version after files with extensions in dir. If concurrent writer takes this
+// version first and write returns ErrKeyVersionExists, tries again with next free version. Returns
+// ErrKeyVersionExists if version wasn't taken after addVersionAttempts tries
+func addVersion(dir string, write func(version uint32) error, extensions ...string) (uint32, error) {
+	for i := 0; i < addVersionAttempts; i++ {
+		version, err := nextVersion(dir, extensions...)
+		if err != nil {
+			return 0, err
+		}
+		if err := write(version); err != ErrKeyVersionExists {
+			if err != nil {
+				return 0, err
+			}
+			return version, nil
+		}
+	}
+	return 0, ErrKeyVersionExists
+}
+
+// AddECKeyPair stores keypair as new version of client's EC keys and returns its version. Safe for concurrent use,
+// concurrent calls get different versions
+func (store *FilesystemKeyStore) AddECKeyPair(clientID []byte, keypair *gothemis.KeyPair) (uint32, error) {
+	dir, err := store.keysDirectory(clientID, ecKeysDirectory)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(dir, directoryPerm); err != nil {
+		return 0, err
+	}
+	public, err := keypair.Public.Marshal()
+	if err != nil {
+		return 0, err
+	}
+	private, err := keypair.Private.Marshal()
+	if err != nil {
+		return 0, err
+	}
+	defer gothemis.Zeroize(private)
+	return addVersion(dir, func(version uint32) error {
+		privatePath := relativeKeyPath(clientID, ecKeysDirectory, version, privateKeyExtension)
+		encrypted, err := store.encrypt(private, privatePath)
+		if err != nil {
+			return err
+		}
+		defer gothemis.Zeroize(encrypted)
+		// version becomes visible when private key written so public key written first
+		publicPath := relativeKeyPath(clientID, ecKeysDirectory, version, publicKeyExtension)
+		publicFile := filepath.Join(store.dir, publicPath)
+		if err := writeFileAtomic(publicFile, public, publicKeyPerm); err != nil {
+			return err
+		}
+		err = writeFileAtomic(filepath.Join(store.dir, privatePath), encrypted, privateKeyPerm)
+		if err != nil && err != ErrKeyVersionExists {
+			// public key of failed version not needed, version stays taken only on clash with concurrent writer
+			os.Remove(publicFile)
+		}
+		return err
+	}, privateKeyExtension, publicKeyExtension)
+}
+
+// GenerateECKeyPair generates and stores new version of client's EC keys
+func (store *FilesystemKeyStore) GenerateECKeyPair(clientID []byte) (uint32, error) {
+	keypair, err := gothemis.NewECKeyPair()
+	if err != nil {
+		return 0, err
+	}
+	defer keypair.Private.Zeroize()
+	return store.AddECKeyPair(clientID, keypair)
+}
+
+// ecKeyVersions return versions of client's complete EC key pairs from newest to oldest
+func (store *FilesystemKeyStore) ecKeyVersions(clientID []byte) ([]uint32, error) {
+	dir, err := store.keysDirectory(clientID, ecKeysDirectory)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := listVersions(dir, privateKeyExtension)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, ErrKeyNotFound
+	}
+	return versions, nil
+}
+
+func (store *FilesystemKeyStore) readKey(clientID []byte, keysDirectory string, version uint32, extension string) ([]byte, error) {
+	path := relativeKeyPath(clientID, keysDirectory, version, extension)
+	data, err := os.ReadFile(filepath.Join(store.dir, path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrKeyNotFound
+		}
+		return nil, err
+	}
+	if extension == publicKeyExtension {
+		return data, nil
+	}
+	decrypted, err := store.decrypt(data, path)
+	if err != nil {
+		return nil, err
+	}
+	return decrypted, nil
+}
+
+// ECKeyPair return client's EC keys with version
+func (store *FilesystemKeyStore) ECKeyPair(clientID []byte, version uint32) (*gothemis.KeyPair, error) {
+	if _, err := store.keysDirectory(clientID, ecKeysDirectory); err != nil {
+		return nil, err
+	}
+	private, err := store.privateKey(clientID, version)
+	if err != nil {
+		return nil, err
+	}
+	publicData, err := store.readKey(clientID, ecKeysDirectory, version, publicKeyExtension)
+	if err != nil {
+		return nil, err
+	}
+	public, err := gothemis.UnmarshalThemisECPublicKey(publicData)
+	if err != nil {
+		return nil, err
+	}
+	return &gothemis.KeyPair{Private: private, Public: public}, nil
+}
+
+func (store *FilesystemKeyStore) privateKey(clientID []byte, version uint32) (*gothemis.PrivateECKey, error) {
+	data, err := store.readKey(clientID, ecKeysDirectory, version, privateKeyExtension)
+	if err != nil {
+		return nil, err
+	}
+	defer gothemis.Zeroize(data)
+	return gothemis.UnmarshalThemisECPrivateKey(data)
+}
+
+// CurrentECKeyPair return newest version of client's EC keys and its version
+func (store *FilesystemKeyStore) CurrentECKeyPair(clientID []byte) (*gothemis.KeyPair, uint32, error) {
+	versions, err := store.ecKeyVersions(clientID)
+	if err != nil {
+		return nil, 0, err
+	}
+	keypair, err := store.ECKeyPair(clientID, versions[0])
+	if err != nil {
+		return nil, 0, err
+	}
+	return keypair, versions[0], nil
+}
+
+// PublicKey return newest version of client's public key used for encryption
+func (store *FilesystemKeyStore) PublicKey(clientID []byte) (*gothemis.PublicECKey, error) {
+	versions, err := store.ecKeyVersions(clientID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := store.readKey(clientID, ecKeysDirectory, versions[0], publicKeyExtension)
+	if err != nil {
+		return nil, err
+	}
+	return gothemis.UnmarshalThemisECPublicKey(data)
+}
+
+// PrivateKeys return all versions of client's private keys for decryption from newest to oldest
+func (store *FilesystemKeyStore) PrivateKeys(clientID []byte) ([]*gothemis.PrivateECKey, error) {
+	versions, err := store.ecKeyVersions(clientID)
+	if err != nil {
+		return nil, err
+	}
+	privateKeys := make([]*gothemis.PrivateECKey, 0, len(versions))
+	for _, version := range versions {
+		private, err := store.privateKey(clientID, version)
+		if err != nil {
+			for _, key := range privateKeys {
+				key.Zeroize()
+			}
+			return nil, err
+		}
+		privateKeys = append(privateKeys, private)
+	}
+	return privateKeys, nil
+}
+
+// AddSymmetricKey stores key as new version of client's symmetric keys and returns its version. Safe for concurrent
+// use, concurrent calls get different versions
+func (store *FilesystemKeyStore) AddSymmetricKey(clientID []byte, key []byte) (uint32, error) {
+	if len(key) == 0 {
+		return 0, gothemis.ErrMissingKey
+	}
+	dir, err := store.keysDirectory(clientID, symmetricKeysDirectory)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(dir, directoryPerm); err != nil {
+		return 0, err
+	}
+	return addVersion(dir, func(version uint32) error {
+		path := relativeKeyPath(clientID, symmetricKeysDirectory, version, symmetricKeyExtension)
+		encrypted, err := store.encrypt(key, path)
+		if err != nil {
+			return err
+		}
+		defer gothemis.Zeroize(encrypted)
+		return writeFileAtomic(filepath.Join(store.dir, path), encrypted, privateKeyPerm)
+	}, symmetricKeyExtension)
+}
+
+// GenerateSymmetricKey generates and stores new version of client's symmetric key
+func (store *FilesystemKeyStore) GenerateSymmetricKey(clientID []byte) (uint32, error) {
+	key := make([]byte, gothemis.SymmetricKeySize)
+	defer gothemis.Zeroize(key)
+	if _, err := rand.Read(key); err != nil {
+		return 0, err
+	}
+	return store.AddSymmetricKey(clientID, key)
+}
+
+func (store *FilesystemKeyStore) symmetricKeyVersions(clientID []byte) ([]uint32, error) {
+	dir, err := store.keysDirectory(clientID, symmetricKeysDirectory)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := listVersions(dir, symmetricKeyExtension)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, ErrKeyNotFound
+	}
+	return versions, nil
+}
+
+// CurrentSymmetricKey return newest version of client's symmetric key used for encryption and its version. Caller
+// should Destroy key after use
+func (store *FilesystemKeyStore) CurrentSymmetricKey(clientID []byte) (*gothemis.SecretBytes, uint32, error) {
+	versions, err := store.symmetricKeyVersions(clientID)
+	if err != nil {
+		return nil, 0, err
+	}
+	key, err := store.readKey(clientID, symmetricKeysDirectory, versions[0], symmetricKeyExtension)
+	if err != nil {
+		return nil, 0, err
+	}
+	return gothemis.NewSecretBytesFromBytes(key), versions[0], nil
+}
+
+// SymmetricKeys return all versions of client's symmetric keys for decryption from newest to oldest. Caller should
+// Destroy keys after use
+func (store *FilesystemKeyStore) SymmetricKeys(clientID []byte) ([]*gothemis.SecretBytes, error) {
+	versions, err := store.symmetricKeyVersions(clientID)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]*gothemis.SecretBytes, 0, len(versions))
+	for _, version := range versions {
+		key, err := store.readKey(clientID, symmetricKeysDirectory, version, symmetricKeyExtension)
+		if err != nil {
+			for _, key := range keys {
+				key.Destroy()
+			}
+			return nil, err
+		}
+		keys = append(keys, gothemis.NewSecretBytesFromBytes(key))
+	}
+	return keys, nil
+}
@@ -0,0 +1,343 @@
+package keystore
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/lagovas/gothemis"
+)
+
+var testClientID = []byte("client_1")
+
+func TestECKeyPairVersions(t *testing.T) {
+	store, err := NewFilesystemKeyStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := store.CurrentECKeyPair(testClientID); err != ErrKeyNotFound {
+		t.Fatal("expected ErrKeyNotFound", err)
+	}
+	var expectedPublicKeys [][]byte
+	for i := 1; i <= 3; i++ {
+		version, err := store.GenerateECKeyPair(testClientID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if version != uint32(i) {
+			t.Fatalf("incorrect version %d, expected %d", version, i)
+		}
+		keypair, current, err := store.CurrentECKeyPair(testClientID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if current != version {
+			t.Fatal("current version not equal to generated version")
+		}
+		public, err := keypair.Public.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectedPublicKeys = append(expectedPublicKeys, public)
+	}
+	public, err := store.PublicKey(testClientID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicBytes, err := public.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(publicBytes, expectedPublicKeys[2]) {
+		t.Fatal("public key is not current")
+	}
+
+	privateKeys, err := store.PrivateKeys(testClientID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(privateKeys) != 3 {
+		t.Fatal("incorrect count of private keys")
+	}
+	// newest key first
+	for i, private := range privateKeys {
+		public, err := private.PublicKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		publicBytes, err := public.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(publicBytes, expectedPublicKeys[2-i]) {
+			t.Fatalf("private key %d doesn't match public key", i)
+		}
+	}
+
+	// other clients have own keys
+	if _, err := store.PrivateKeys([]byte("client_2")); err != ErrKeyNotFound {
+		t.Fatal("expected ErrKeyNotFound", err)
+	}
+}
+
+func TestInvalidClientID(t *testing.T) {
+	store, err := NewFilesystemKeyStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, clientID := range [][]byte{nil, []byte("../client"), []byte("client/1"), bytes.Repeat([]byte("a"), 257)} {
+		if _, err := store.GenerateECKeyPair(clientID); err != ErrInvalidClientID {
+			t.Fatal("expected ErrInvalidClientID", err)
+		}
+		if _, err := store.PrivateKeys(clientID); err != ErrInvalidClientID {
+			t.Fatal("expected ErrInvalidClientID", err)
+		}
+		if _, err := store.GenerateSymmetricKey(clientID); err != ErrInvalidClientID {
+			t.Fatal("expected ErrInvalidClientID", err)
+		}
+	}
+}
+
+func TestSymmetricKeyVersions(t *testing.T) {
+	store, err := NewFilesystemKeyStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	firstKey := []byte("first key")
+	secondKey := []byte("second key")
+	if _, err := store.AddSymmetricKey(testClientID, firstKey); err != nil {
+		t.Fatal(err)
+	}
+	version, err := store.AddSymmetricKey(testClientID, secondKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, current, err := store.CurrentSymmetricKey(testClientID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current != version || !bytes.Equal(key.Bytes(), secondKey) {
+		t.Fatal("incorrect current symmetric key")
+	}
+	keys, err := store.SymmetricKeys(testClientID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || !bytes.Equal(keys[0].Bytes(), secondKey) || !bytes.Equal(keys[1].Bytes(), firstKey) {
+		t.Fatal("incorrect symmetric keys")
+	}
+	if _, err := store.AddSymmetricKey(testClientID, nil); err != gothemis.ErrMissingKey {
+		t.Fatal("expected ErrMissingKey", err)
+	}
+}
+
+func TestConcurrentAddKeys(t *testing.T) {
+	store, err := NewEncryptedFilesystemKeyStore(t.TempDir(), []byte("master key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const count = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*count)
+	for i := 0; i < count; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, err := store.GenerateECKeyPair(testClientID)
+			errs <- err
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := store.GenerateSymmetricKey(testClientID)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	privateKeys, err := store.PrivateKeys(testClientID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	symmetricKeys, err := store.SymmetricKeys(testClientID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(privateKeys) != count || len(symmetricKeys) != count {
+		t.Fatalf("expected %d keys of each type, got %d EC and %d symmetric", count, len(privateKeys), len(symmetricKeys))
+	}
+}
+
+func TestEncryptedKeyStore(t *testing.T) {
+	dir := t.TempDir()
+	masterKey := []byte("master key")
+	store, err := NewEncryptedFilesystemKeyStore(dir, masterKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keypair, err := gothemis.NewECKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.AddECKeyPair(testClientID, keypair); err != nil {
+		t.Fatal(err)
+	}
+	symmetricKey := []byte("symmetric key")
+	if _, err := store.AddSymmetricKey(testClientID, symmetricKey); err != nil {
+		t.Fatal(err)
+	}
+
+	privateBytes, err := keypair.Private.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, err := os.ReadFile(filepath.Join(dir, string(testClientID), ecKeysDirectory, "1"+privateKeyExtension))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(stored, privateBytes[len(privateBytes)-32:]) {
+		t.Fatal("private key stored unencrypted")
+	}
+	stored, err = os.ReadFile(filepath.Join(dir, string(testClientID), symmetricKeysDirectory, "1"+symmetricKeyExtension))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(stored, symmetricKey) {
+		t.Fatal("symmetric key stored unencrypted")
+	}
+
+	privateKeys, err := store.PrivateKeys(testClientID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := privateKeys[0].Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(loaded, privateBytes) {
+		t.Fatal("loaded private key not equal to stored")
+	}
+	key, _, err := store.CurrentSymmetricKey(testClientID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(key.Bytes(), symmetricKey) {
+		t.Fatal("loaded symmetric key not equal to stored")
+	}
+
+	otherStore, err := NewEncryptedFilesystemKeyStore(dir, []byte("other master key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := otherStore.PrivateKeys(testClientID); err == nil {
+		t.Fatal("expected error with incorrect master key")
+	}
+
+	// encrypted key can't be moved to other client
+	otherClientDir := filepath.Join(dir, "client_2", symmetricKeysDirectory)
+	if err := os.MkdirAll(otherClientDir, directoryPerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(otherClientDir, "1"+symmetricKeyExtension), stored, privateKeyPerm); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := store.CurrentSymmetricKey([]byte("client_2")); err == nil {
+		t.Fatal("expected error on key moved to other client")
+	}
+}
+
+func TestIncompleteKeyPair(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFilesystemKeyStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.GenerateECKeyPair(testClientID); err != nil {
+		t.Fatal(err)
+	}
+	// public key left by interrupted write of second version
+	ecDir := filepath.Join(dir, string(testClientID), ecKeysDirectory)
+	if err := os.WriteFile(filepath.Join(ecDir, "2"+publicKeyExtension), []byte("data"), publicKeyPerm); err != nil {
+		t.Fatal(err)
+	}
+	if _, current, err := store.CurrentECKeyPair(testClientID); err != nil || current != 1 {
+		t.Fatal("incomplete key pair used as current", current, err)
+	}
+	version, err := store.GenerateECKeyPair(testClientID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 3 {
+		t.Fatal("incorrect version after incomplete key pair", version)
+	}
+	if err := writeFileAtomic(filepath.Join(ecDir, "3"+privateKeyExtension), []byte("data"), privateKeyPerm); err != ErrKeyVersionExists {
+		t.Fatal("expected ErrKeyVersionExists", err)
+	}
+	files, err := os.ReadDir(ecDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 5 {
+		t.Fatal("temporary files not removed")
+	}
+}
+
+func TestKeySource(t *testing.T) {
+	store, err := NewEncryptedFilesystemKeyStore(t.TempDir(), []byte("master key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.GenerateECKeyPair(testClientID); err != nil {
+		t.Fatal(err)
+	}
+	oldPublic, err := store.PublicKey(testClientID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte("some data")
+	acrastruct, err := gothemis.CreateAcrastruct(data, oldPublic, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// rotate keys, old AcraStructs still can be decrypted
+	if _, err := store.GenerateECKeyPair(testClientID); err != nil {
+		t.Fatal(err)
+	}
+	var source gothemis.PrivateKeySource = store
+	decrypted, err := gothemis.DecryptAcrastructWithKeySource(acrastruct, source, testClientID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decrypted, data) {
+		t.Fatal("decrypted data not equal to source data")
+	}
+
+	peer, err := gothemis.NewECKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	smessage, err := gothemis.NewSecureMessage(peer.Private, oldPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrapped, err := smessage.Wrap(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypted, err = gothemis.UnwrapSecureMessageWithKeySource(wrapped, source, testClientID, peer.Public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decrypted, data) {
+		t.Fatal("decrypted data not equal to source data")
+	}
+	if _, err := gothemis.DecryptAcrastructWithKeySource(acrastruct, source, []byte("client_2"), nil); err != ErrKeyNotFound {
+		t.Fatal("expected ErrKeyNotFound", err)
+	}
+}
@@ -2,24 +2,36 @@ package gothemis
 
 import (
 	"bytes"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
 	"encoding/binary"
 	"errors"
 	"math"
-	"math/big"
 )
 
 type secureMessage struct {
 	privateKey *PrivateECKey
 	publicKey  *PublicECKey
+	// keys converted once for crypto/ecdh because conversion of private key computes its public point. crypto/ecdh
+	// can't wipe its copy of private key so it stays in memory until collected
+	ecdhPrivate *ecdh.PrivateKey
+	ecdhPublic  *ecdh.PublicKey
 }
 
 var ErrKeysUseDifferentCurves = errors.New("private and public keys use different curves")
 
 const (
-	themisSecureMessage            = 0x26040000
-	ThemisSecureMessageEncrypted   = themisSecureMessage ^ 0x00002700
-	ThemisSecureMessageECEncrypted = ThemisSecureMessageEncrypted ^ 0x00000020
-	themisSecureMessageHeaderSize  = 8
+	themisSecureMessage             = 0x26040000
+	ThemisSecureMessageEncrypted    = themisSecureMessage ^ 0x00002700
+	ThemisSecureMessageECEncrypted  = ThemisSecureMessageEncrypted ^ 0x00000020
+	ThemisSecureMessageRSAEncrypted = ThemisSecureMessageEncrypted ^ 0x00000010
+	themisSecureMessageHeaderSize   = 8
+	// rsaSecureMessageHeaderSize message header and length of encrypted password as uint32
+	rsaSecureMessageHeaderSize = themisSecureMessageHeaderSize + 4
+	// themisRSASymmetricPasswordLength length of random password used as Secure Cell key in RSA mode
+	themisRSASymmetricPasswordLength = 70
 )
 
 func IsSecureMessageEncrypted(tag uint32) bool {
@@ -73,26 +85,43 @@ func (smd *SecureMessageData) MessageSize() uint32 {
 	return binary.LittleEndian.Uint32(smd.length[:])
 }
 
+// NewSecureMessage return Secure Message with own private and peer's public EC keys. Secure Message keeps crypto/ecdh
+// copy of private key that Zeroize of private can't wipe
 func NewSecureMessage(private *PrivateECKey, public *PublicECKey) (*secureMessage, error) {
 	if TagToCurve(private.tag[:]) != TagToCurve(public.tag[:]) {
 		return nil, ErrKeysUseDifferentCurves
 	}
-	return &secureMessage{private, public}, nil
+	ecdhPrivate, err := private.private.ECDH()
+	if err != nil {
+		return nil, ThemisError(err.Error())
+	}
+	ecdhPublic, err := public.Public().ECDH()
+	if err != nil {
+		return nil, ThemisError(err.Error())
+	}
+	return &secureMessage{privateKey: private, publicKey: public, ecdhPrivate: ecdhPrivate, ecdhPublic: ecdhPublic}, nil
+}
+
+// sharedSecret return X coordinate of ECDH shared point aligned to curve size as Themis uses. Computed by
+// constant-time crypto/ecdh
+func (smessage *secureMessage) sharedSecret() (*SecretBytes, error) {
+	secret, err := smessage.ecdhPrivate.ECDH(smessage.ecdhPublic)
+	if err != nil {
+		return nil, err
+	}
+	return NewSecretBytesFromBytes(secret), nil
 }
 
 func (smessage *secureMessage) Wrap(data []byte) ([]byte, error) {
-	curve := TagToCurve(smessage.privateKey.tag[:])
-	d := smessage.privateKey.private.D.Bytes()
-	defer Zeroize(d)
-	x, _ := curve.ScalarMult(smessage.publicKey.x, smessage.publicKey.y, d)
-	sharedKey := alignPointInBytes(curve.Params().BitSize/8, x)
-	// zeroize temp key
-	x.Set(new(big.Int))
-	encrypted, err := CellSealEncrypt(sharedKey, data, nil)
+	sharedKey, err := smessage.sharedSecret()
+	if err != nil {
+		return nil, ThemisError(err.Error())
+	}
+	defer sharedKey.Destroy()
+	encrypted, err := CellSealEncrypt(sharedKey.Bytes(), data, nil)
 	if err != nil {
 		return nil, ThemisError(err.Error())
 	}
-	Zeroize(sharedKey)
 	smd, err := NewSecureMessageECEncrypted(encrypted)
 	if err != nil {
 		return nil, ThemisError(err.Error())
@@ -101,6 +130,7 @@ func (smessage *secureMessage) Wrap(data []byte) ([]byte, error) {
 }
 
 var ErrInvalidMessageLength = errors.New("message has incorrect length")
+var ErrInvalidSecureMessageType = errors.New("incorrect type of secure message")
 
 func (smessage *secureMessage) Unwrap(data []byte) ([]byte, error) {
 	messageData, err := SecureMessageDataFromMessage(data)
@@ -110,19 +140,96 @@ func (smessage *secureMessage) Unwrap(data []byte) ([]byte, error) {
 	if messageData.MessageSize() != uint32(len(data)) {
 		return nil, ErrInvalidMessageLength
 	}
-	curve := TagToCurve(smessage.privateKey.tag[:])
-	d := smessage.privateKey.private.D.Bytes()
-	defer Zeroize(d)
-	x, _ := curve.ScalarMult(smessage.publicKey.x, smessage.publicKey.y, d)
+	if binary.LittleEndian.Uint32(messageData.messageType[:]) != ThemisSecureMessageECEncrypted {
+		return nil, ErrInvalidSecureMessageType
+	}
+	sharedKey, err := smessage.sharedSecret()
+	if err != nil {
+		return nil, ThemisError(err.Error())
+	}
+	defer sharedKey.Destroy()
+	decrypted, err := CellSealDecrypt(sharedKey.Bytes(), messageData.data, nil)
+	if err != nil {
+		return nil, ThemisError(err.Error())
+	}
+	return decrypted, nil
+}
+
+type secureMessageRSA struct {
+	privateKey *PrivateRSAKey
+	publicKey  *PublicRSAKey
+}
+
+var ErrMissingRSAKey = errors.New("RSA key required for operation not specified")
+
+// NewSecureMessageRSA return Secure Message that encrypts data with peer's public key and decrypts with own private
+// key. One of keys may be nil if only one operation used
+func NewSecureMessageRSA(private *PrivateRSAKey, public *PublicRSAKey) (*secureMessageRSA, error) {
+	if private == nil && public == nil {
+		return nil, ErrMissingRSAKey
+	}
+	return &secureMessageRSA{private, public}, nil
+}
 
-	sharedKey := alignPointInBytes(curve.Params().BitSize/8, x)
-	// zeroize temp key
-	x.Set(new(big.Int))
+// Wrap encrypts data with random password in Seal mode and password with RSA-OAEP. Output has next structure:
+// message type[uint32], message length[uint32], encrypted password length[uint32], encrypted password, Secure Cell
+func (smessage *secureMessageRSA) Wrap(data []byte) ([]byte, error) {
+	if smessage.publicKey == nil {
+		return nil, ErrMissingRSAKey
+	}
+	password := NewSecretBytes(themisRSASymmetricPasswordLength)
+	defer password.Destroy()
+	if _, err := rand.Read(password.Bytes()); err != nil {
+		return nil, err
+	}
+	encryptedPassword, err := rsa.EncryptOAEP(sha1.New(), rand.Reader, smessage.publicKey.public, password.Bytes(), nil)
+	if err != nil {
+		return nil, ThemisError(err.Error())
+	}
+	encrypted, err := CellSealEncrypt(password.Bytes(), data, nil)
+	if err != nil {
+		return nil, ThemisError(err.Error())
+	}
+	length := rsaSecureMessageHeaderSize + len(encryptedPassword) + len(encrypted)
+	if length > math.MaxUint32 {
+		return nil, ErrDataTooLongForUint32
+	}
+	output := make([]byte, rsaSecureMessageHeaderSize, length)
+	binary.LittleEndian.PutUint32(output[:4], ThemisSecureMessageRSAEncrypted)
+	binary.LittleEndian.PutUint32(output[4:8], uint32(length))
+	binary.LittleEndian.PutUint32(output[8:12], uint32(len(encryptedPassword)))
+	output = append(output, encryptedPassword...)
+	return append(output, encrypted...), nil
+}
 
-	decrypted, err := CellSealDecrypt(sharedKey, messageData.data, nil)
+func (smessage *secureMessageRSA) Unwrap(data []byte) ([]byte, error) {
+	if smessage.privateKey == nil {
+		return nil, ErrMissingRSAKey
+	}
+	if len(data) < rsaSecureMessageHeaderSize {
+		return nil, ErrInvalidMessageLength
+	}
+	messageData, err := SecureMessageDataFromMessage(data)
+	if err != nil {
+		return nil, err
+	}
+	if binary.LittleEndian.Uint32(messageData.messageType[:]) != ThemisSecureMessageRSAEncrypted {
+		return nil, ErrInvalidSecureMessageType
+	}
+	passwordLength := uint64(binary.LittleEndian.Uint32(data[8:12]))
+	if passwordLength > uint64(len(data)-rsaSecureMessageHeaderSize) {
+		return nil, ErrInvalidMessageLength
+	}
+	encryptedPassword := data[rsaSecureMessageHeaderSize : rsaSecureMessageHeaderSize+passwordLength]
+	decryptedPassword, err := rsa.DecryptOAEP(sha1.New(), rand.Reader, smessage.privateKey.private, encryptedPassword, nil)
+	if err != nil {
+		return nil, ThemisError(err.Error())
+	}
+	password := NewSecretBytesFromBytes(decryptedPassword)
+	defer password.Destroy()
+	decrypted, err := CellSealDecrypt(password.Bytes(), data[rsaSecureMessageHeaderSize+passwordLength:], nil)
 	if err != nil {
 		return nil, ThemisError(err.Error())
 	}
-	Zeroize(sharedKey)
 	return decrypted, nil
 }
@@ -2,6 +2,7 @@ package gothemis
 
 import (
 	"bytes"
+	"crypto/elliptic"
 	"crypto/rand"
 	"encoding/binary"
 	"testing"
@@ -76,6 +77,76 @@ func TestSecureMessageWrapUnwrap(t *testing.T) {
 	}
 	testSecureMessageWrapUnwrap(keypairAlice, keypairBob, t)
 }
+func TestSecureMessageWrapUnwrapCurves(t *testing.T) {
+	for _, curve := range []elliptic.Curve{elliptic.P384(), elliptic.P521()} {
+		alice, err := NewECKeyPairWithCurve(curve)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bob, err := NewECKeyPairWithCurve(curve)
+		if err != nil {
+			t.Fatal(err)
+		}
+		themisAlice, err := alice.ToThemisKeyPair()
+		if err != nil {
+			t.Fatal(err)
+		}
+		themisBob, err := bob.ToThemisKeyPair()
+		if err != nil {
+			t.Fatal(err)
+		}
+		testSecureMessageWrapUnwrap(themisAlice, themisBob, t)
+	}
+	p256, err := NewECKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p384, err := NewECKeyPairWithCurve(elliptic.P384())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewSecureMessage(p256.Private, p384.Public); err != ErrKeysUseDifferentCurves {
+		t.Fatal("expected ErrKeysUseDifferentCurves")
+	}
+}
+
+func TestSharedSecret(t *testing.T) {
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		alice, err := NewECKeyPairWithCurve(curve)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bob, err := NewECKeyPairWithCurve(curve)
+		if err != nil {
+			t.Fatal(err)
+		}
+		aliceMessage, err := NewSecureMessage(alice.Private, bob.Public)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bobMessage, err := NewSecureMessage(bob.Private, alice.Public)
+		if err != nil {
+			t.Fatal(err)
+		}
+		secret, err := aliceMessage.sharedSecret()
+		if err != nil {
+			t.Fatal(err)
+		}
+		peerSecret, err := bobMessage.sharedSecret()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(secret.Bytes(), peerSecret.Bytes()) {
+			t.Fatal("shared secrets of peers not equal")
+		}
+		// same key as generic curve arithmetic used before crypto/ecdh
+		x, _ := curve.ScalarMult(bob.Public.x, bob.Public.y, alice.Private.private.D.Bytes())
+		if !bytes.Equal(secret.Bytes(), alignPointInBytes(curveKeySize(curve), x)) {
+			t.Fatal("shared secret not equal to X coordinate of shared point")
+		}
+	}
+}
+
 func testSecureMessageWrapUnwrap(keypairAlice, keypairBob *keys.Keypair, t *testing.T) {
 	for i := 0; i < 1000; i++ {
 		alicePrivate, err := UnmarshalThemisECPrivateKey(keypairAlice.Private.Value)
@@ -147,6 +218,107 @@ func testSecureMessageWrapUnwrap(keypairAlice, keypairBob *keys.Keypair, t *test
 	}
 }
 
+func TestSecureMessageRSAWrapUnwrap(t *testing.T) {
+	alice, err := NewRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	themisAlice, err := alice.ToThemisKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	themisBob, err := keys.New(keys.TypeRSA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bobPrivate, err := UnmarshalThemisRSAPrivateKey(themisBob.Private.Value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bobPublic, err := UnmarshalThemisRSAPublicKey(themisBob.Public.Value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aliceSM, err := NewSecureMessageRSA(alice.Private, bobPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bobSM, err := NewSecureMessageRSA(bobPrivate, alice.Public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aliceThemisSM := message.New(themisAlice.Private, themisBob.Public)
+	bobThemisSM := message.New(themisBob.Private, themisAlice.Public)
+
+	testData := make([]byte, 100)
+	if _, err := rand.Read(testData); err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := aliceSM.Wrap(testData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if binary.LittleEndian.Uint32(encrypted[:4]) != ThemisSecureMessageRSAEncrypted {
+		t.Fatal("incorrect message type")
+	}
+	decrypted, err := bobSM.Unwrap(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decrypted, testData) {
+		t.Fatal("Decrypted data not equal to source data")
+	}
+	decrypted, err = bobThemisSM.Unwrap(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decrypted, testData) {
+		t.Fatal("Decrypted data not equal to source data")
+	}
+	themisEncrypted, err := aliceThemisSM.Wrap(testData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypted, err = bobSM.Unwrap(themisEncrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decrypted, testData) {
+		t.Fatal("Decrypted data not equal to source data")
+	}
+
+	// message encrypted for Bob can't be decrypted by Alice
+	if _, err := aliceSM.Unwrap(encrypted); err == nil {
+		t.Fatal("expected error on decryption with incorrect key")
+	}
+	if _, err := bobSM.Unwrap(encrypted[:len(encrypted)-1]); err != ErrInvalidMessageLength {
+		t.Fatal("expected ErrInvalidMessageLength", err)
+	}
+	onlyPublic, err := NewSecureMessageRSA(nil, bobPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := onlyPublic.Unwrap(encrypted); err != ErrMissingRSAKey {
+		t.Fatal("expected ErrMissingRSAKey", err)
+	}
+	if _, err := NewSecureMessageRSA(nil, nil); err != ErrMissingRSAKey {
+		t.Fatal("expected ErrMissingRSAKey", err)
+	}
+
+	// EC Secure Message must reject RSA messages
+	ecAlice, err := NewECKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecSM, err := NewSecureMessage(ecAlice.Private, ecAlice.Public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ecSM.Unwrap(encrypted); err != ErrInvalidSecureMessageType {
+		t.Fatal("expected ErrInvalidSecureMessageType", err)
+	}
+}
+
 func TestNewCRC32(t *testing.T) {
 	keypair, err := keys.New(keys.TypeEC)
 	if err != nil {
@@ -197,6 +369,33 @@ func BenchmarkSecureMessage(b *testing.B) {
 	}
 }
 
+func BenchmarkSecureMessageWrapCurves(b *testing.B) {
+	data := make([]byte, 100)
+	rand.Read(data)
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		b.Run(curve.Params().Name, func(b *testing.B) {
+			alice, err := NewECKeyPairWithCurve(curve)
+			if err != nil {
+				b.Fatal(err)
+			}
+			bob, err := NewECKeyPairWithCurve(curve)
+			if err != nil {
+				b.Fatal(err)
+			}
+			sm, err := NewSecureMessage(alice.Private, bob.Public)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := sm.Wrap(data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
 func BenchmarkThemisSecureMessage(b *testing.B) {
 	data := make([]byte, 100)
 	rand.Read(data)
@@ -2,8 +2,10 @@ package gothemis
 
 import (
 	"bytes"
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/rand"
+	"crypto/rsa"
 	"crypto/sha256"
 	"encoding/asn1"
 	"encoding/binary"
@@ -33,35 +35,99 @@ const (
 	signedMessageStaticOverhead = 12 // sizeof(messageHeader) + sizeof(signedMessageHeader)
 )
 
-func signECDSA(data []byte, private *ecdsa.PrivateKey)([]byte, error){
-	mac := sha256.Sum256(data)
-	signature, err := private.Sign(rand.Reader, mac[:], nil)
-	if err != nil {
-		return nil, err
+var ErrUnsupportedSigner = errors.New("signer has unsupported public key type")
+
+// isEmptySigner return true if signer is nil or typed nil of Themis private key
+func isEmptySigner(signer crypto.Signer) bool {
+	switch key := signer.(type) {
+	case nil:
+		return true
+	case *PrivateECKey:
+		return key == nil || key.private == nil
+	case *PrivateRSAKey:
+		return key == nil || key.private == nil
 	}
-	return signature, nil
+	return false
 }
 
-func Sign(data []byte, privateKey *PrivateECKey) ([]byte, error) {
-	signature, err := signECDSA(data, privateKey.private)
-	if err != nil {
-		return nil, err
+// signECDSA return ECDSA signature of SHA-256 digest of data made by signer with EC key
+func signECDSA(data []byte, signer crypto.Signer) ([]byte, error) {
+	if _, ok := signer.Public().(*ecdsa.PublicKey); !ok {
+		return nil, ErrUnsupportedSigner
+	}
+	mac := sha256.Sum256(data)
+	return signer.Sign(rand.Reader, mac[:], crypto.SHA256)
+}
+
+// Sign return signed Secure Message with data. signer may be *PrivateECKey, *PrivateRSAKey or any crypto.Signer with
+// ECDSA or RSA key, e.g. key kept by HSM or signing daemon. Message type and algorithm chosen by type of signer's
+// public key: ECDSA with SHA-256 or RSA-PSS with SHA-256 as Themis uses
+func Sign(data []byte, signer crypto.Signer) ([]byte, error) {
+	if isEmptySigner(signer) {
+		return nil, ErrEmptyPrivateKey
 	}
+	switch signer.Public().(type) {
+	case *ecdsa.PublicKey:
+		signature, err := signECDSA(data, signer)
+		if err != nil {
+			return nil, err
+		}
+		return marshalSignedMessage(THEMIS_SECURE_MESSAGE_EC_SIGNED, data, signature), nil
+	case *rsa.PublicKey:
+		signature, err := signRSAPSS(data, signer)
+		if err != nil {
+			return nil, err
+		}
+		return marshalSignedMessage(THEMIS_SECURE_MESSAGE_RSA_SIGNED, data, signature), nil
+	}
+	return nil, ErrUnsupportedSigner
+}
+
+// marshalSignedMessage return signed Secure Message with data and signature
+func marshalSignedMessage(messageType uint32, data, signature []byte) []byte {
 	output := make([]byte, len(data)+len(signature)+signedMessageStaticOverhead)
-	binary.LittleEndian.PutUint32(output[:4], uint32(THEMIS_SECURE_MESSAGE_EC_SIGNED))
+	binary.LittleEndian.PutUint32(output[:4], messageType)
 	binary.LittleEndian.PutUint32(output[4:8], uint32(len(data)))
 	binary.LittleEndian.PutUint32(output[8:12], uint32(len(signature)))
 	writer := bytes.NewBuffer(output[:signedMessageStaticOverhead])
 	binary.Write(writer, binary.LittleEndian, data)
 	binary.Write(writer, binary.LittleEndian, signature)
-	return output, nil
+	return output
 }
 
 var ErrVerify = errors.New("Failed to verify message")
 
+var ErrUnsupportedPublicKey = errors.New("unsupported type of public key")
+
+// themisPublicKey return public as *PublicECKey or *PublicRSAKey. Public keys of crypto/ecdsa and crypto/rsa converted
+// to Themis keys, so public key of any signer accepted by Sign may be used for verification. Returns
+// ErrInvalidPublicKey for nil and zero value Themis keys
+func themisPublicKey(public crypto.PublicKey) (crypto.PublicKey, error) {
+	switch key := public.(type) {
+	case *PublicECKey:
+		if key == nil || key.x == nil || key.y == nil {
+			return nil, ErrInvalidPublicKey
+		}
+		return key, nil
+	case *PublicRSAKey:
+		if key == nil || key.public == nil {
+			return nil, ErrInvalidPublicKey
+		}
+		return key, nil
+	case *ecdsa.PublicKey:
+		return FromECDSAPublicKey(key)
+	case *rsa.PublicKey:
+		if key == nil || key.N == nil {
+			return nil, ErrInvalidRSAKey
+		}
+		return newPublicRSAKey(&rsa.PublicKey{N: new(big.Int).Set(key.N), E: key.E})
+	}
+	return nil, ErrUnsupportedPublicKey
+}
+
 func validateSecureMessageType(messageType uint32) bool {
 	switch messageType {
-	case uint32(THEMIS_SECURE_MESSAGE_EC_SIGNED):
+	case uint32(THEMIS_SECURE_MESSAGE_EC_SIGNED), uint32(THEMIS_SECURE_MESSAGE_RSA_SIGNED):
 		return true
 	}
 	return false
@@ -76,7 +142,14 @@ func parseGoDEREncodedECDSASignature(signature []byte, params *signatureParams)
 	return err
 }
 
-func Verify(data []byte, public *PublicECKey) ([]byte, error) {
+// Verify signed Secure Message and return source data. Algorithm chosen by message type: ECDSA for messages signed with
+// EC keys and RSA-PSS for RSA keys, public should be *PublicECKey or *ecdsa.PublicKey and *PublicRSAKey or
+// *rsa.PublicKey accordingly. Returns ErrUnsupportedPublicKey for other key types
+func Verify(data []byte, public crypto.PublicKey) ([]byte, error) {
+	public, err := themisPublicKey(public)
+	if err != nil {
+		return nil, err
+	}
 	if len(data) < signedMessageStaticOverhead {
 		return nil, ErrVerify
 	}
@@ -86,19 +159,39 @@ func Verify(data []byte, public *PublicECKey) ([]byte, error) {
 	}
 	dataLength := binary.LittleEndian.Uint32(data[4:8])
 	signatureLength := binary.LittleEndian.Uint32(data[8:12])
-	if int(dataLength+signatureLength+signedMessageStaticOverhead) != len(data) {
+	if uint64(dataLength)+uint64(signatureLength)+signedMessageStaticOverhead != uint64(len(data)) {
 		return nil, ErrVerify
 	}
 	signature := data[signedMessageStaticOverhead+dataLength:]
+	sourceMessage := data[signedMessageStaticOverhead : signedMessageStaticOverhead+dataLength]
+	switch messageType {
+	case THEMIS_SECURE_MESSAGE_EC_SIGNED:
+		ecPublic, ok := public.(*PublicECKey)
+		if !ok {
+			return nil, ErrVerify
+		}
+		err = verifyECDSA(sourceMessage, signature, ecPublic)
+	case THEMIS_SECURE_MESSAGE_RSA_SIGNED:
+		rsaPublic, ok := public.(*PublicRSAKey)
+		if !ok {
+			return nil, ErrVerify
+		}
+		err = verifyRSAPSS(sourceMessage, signature, rsaPublic)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return sourceMessage, nil
+}
+
+func verifyECDSA(data, signature []byte, public *PublicECKey) error {
 	sigParams := &signatureParams{R: new(big.Int), S: new(big.Int)}
 	if err := parseGoDEREncodedECDSASignature(signature, sigParams); err != nil {
-		return nil, ErrVerify
+		return ErrVerify
 	}
-	sourceMessage := data[signedMessageStaticOverhead : signedMessageStaticOverhead+dataLength]
-	digest := sha256.Sum256(sourceMessage)
-	verify := ecdsa.Verify(public.Public(), digest[:], sigParams.R, sigParams.S)
-	if !verify {
-		return nil, ErrVerify
+	digest := sha256.Sum256(data)
+	if !ecdsa.Verify(public.Public(), digest[:], sigParams.R, sigParams.S) {
+		return ErrVerify
 	}
-	return sourceMessage, nil
+	return nil
 }
@@ -2,11 +2,16 @@ package gothemis
 
 import (
 	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
+	"encoding/binary"
 	"encoding/hex"
 	"fmt"
 	"github.com/cossacklabs/themis/gothemis/keys"
 	message2 "github.com/cossacklabs/themis/gothemis/message"
+	"io"
 	"testing"
 )
 
@@ -62,6 +67,159 @@ func TestSign(t *testing.T) {
 	}
 }
 
+func TestSignCurves(t *testing.T) {
+	data := []byte(`test`)
+	for _, curve := range []elliptic.Curve{elliptic.P384(), elliptic.P521()} {
+		keypair, err := NewECKeyPairWithCurve(curve)
+		if err != nil {
+			t.Fatal(err)
+		}
+		themisKeypair, err := keypair.ToThemisKeyPair()
+		if err != nil {
+			t.Fatal(err)
+		}
+		signedMessage, err := Sign(data, keypair.Private)
+		if err != nil {
+			t.Fatal(err)
+		}
+		message := message2.New(themisKeypair.Private, themisKeypair.Public)
+		rawMessage, err := message.Verify(signedMessage)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(rawMessage, data) {
+			t.Fatal("Verified data not equal to source data")
+		}
+		themisSigned, err := message.Sign(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rawMessage, err = Verify(themisSigned, keypair.Public)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(rawMessage, data) {
+			t.Fatal("Verified data not equal to source data")
+		}
+	}
+}
+
+// testSigner stands in for signing daemon: signs digests without exposing private key
+type testSigner struct {
+	signer crypto.Signer
+	calls  int
+}
+
+func (s *testSigner) Public() crypto.PublicKey {
+	return s.signer.Public()
+}
+
+func (s *testSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	s.calls++
+	return s.signer.Sign(rand, digest, opts)
+}
+
+func TestSignWithSigner(t *testing.T) {
+	ecKeypair, err := NewECKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKeypair, err := NewRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		private     crypto.Signer
+		public      crypto.PublicKey
+		messageType uint32
+	}{
+		{ecKeypair.Private, ecKeypair.Public, THEMIS_SECURE_MESSAGE_EC_SIGNED},
+		{rsaKeypair.Private, rsaKeypair.Public, THEMIS_SECURE_MESSAGE_RSA_SIGNED},
+	}
+	data := []byte(`test`)
+	for _, tcase := range testCases {
+		signer := &testSigner{signer: tcase.private}
+		signedMessage, err := Sign(data, signer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if signer.calls != 1 {
+			t.Fatal("message not signed by signer")
+		}
+		// public key of signer has crypto/ecdsa or crypto/rsa type
+		for _, public := range []crypto.PublicKey{tcase.public, signer.Public()} {
+			rawMessage, err := Verify(signedMessage, public)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(rawMessage, data) {
+				t.Fatal("Verified data not equal to source data")
+			}
+		}
+		// same format as signed with private key directly
+		expected, err := Sign(data, tcase.private)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if binary.LittleEndian.Uint32(signedMessage[:4]) != tcase.messageType ||
+			!bytes.Equal(signedMessage[:8], expected[:8]) ||
+			!bytes.Equal(signedMessage[signedMessageStaticOverhead:signedMessageStaticOverhead+len(data)], data) {
+			t.Fatal("incorrect format of signed message")
+		}
+	}
+
+	_, ed25519Private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Sign(data, ed25519Private); err != ErrUnsupportedSigner {
+		t.Fatal("expected ErrUnsupportedSigner", err)
+	}
+	signedMessage, err := Sign(data, ecKeypair.Private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Verify(signedMessage, ed25519Private.Public()); err != ErrUnsupportedPublicKey {
+		t.Fatal("expected ErrUnsupportedPublicKey", err)
+	}
+	var nilECPublic *PublicECKey
+	var nilRSAPublic *PublicRSAKey
+	for _, public := range []crypto.PublicKey{nilECPublic, &PublicECKey{}, nilRSAPublic, &PublicRSAKey{}} {
+		if _, err := Verify(signedMessage, public); err != ErrInvalidPublicKey {
+			t.Fatal("expected ErrInvalidPublicKey", err)
+		}
+	}
+	var nilKey *PrivateECKey
+	for _, signer := range []crypto.Signer{nil, nilKey} {
+		if _, err := Sign(data, signer); err != ErrEmptyPrivateKey {
+			t.Fatal("expected ErrEmptyPrivateKey", err)
+		}
+	}
+}
+
+func TestThemisVerifySignedWithSigner(t *testing.T) {
+	keypair, err := NewECKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	themisKeypair, err := keypair.ToThemisKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte(`test`)
+	signedMessage, err := Sign(data, &testSigner{signer: keypair.Private})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rawMessage, err := message2.New(nil, themisKeypair.Public).Verify(signedMessage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rawMessage, data) {
+		t.Fatal("Verified data not equal to source data")
+	}
+}
+
 func BenchmarkSign(b *testing.B) {
 	kp, err := NewECKeyPair()
 	if err != nil {
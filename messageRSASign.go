@@ -0,0 +1,32 @@
+package gothemis
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+)
+
+// rsaPSSOptions same parameters as Themis uses: SHA-256 and maximal salt length on signing, any salt length accepted
+// on verification
+var rsaPSSOptions = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto, Hash: crypto.SHA256}
+
+// signRSAPSS return RSA-PSS signature of SHA-256 digest of data made by signer with RSA key
+func signRSAPSS(data []byte, signer crypto.Signer) ([]byte, error) {
+	digest := sha256.Sum256(data)
+	return signer.Sign(rand.Reader, digest[:], rsaPSSOptions)
+}
+
+// SignRSA return signed Secure Message with data and RSA-PSS signature compatible with Themis. Same as Sign with
+// privateKey
+func SignRSA(data []byte, privateKey *PrivateRSAKey) ([]byte, error) {
+	return Sign(data, privateKey)
+}
+
+func verifyRSAPSS(data, signature []byte, public *PublicRSAKey) error {
+	digest := sha256.Sum256(data)
+	if err := rsa.VerifyPSS(public.Public(), crypto.SHA256, digest[:], signature, rsaPSSOptions); err != nil {
+		return ErrVerify
+	}
+	return nil
+}
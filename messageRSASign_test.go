@@ -0,0 +1,117 @@
+package gothemis
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/cossacklabs/themis/gothemis/keys"
+	"github.com/cossacklabs/themis/gothemis/message"
+)
+
+func TestSignRSA(t *testing.T) {
+	keypair, err := NewRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	themisKeypair, err := keypair.ToThemisKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte(`test`)
+	signedMessage, err := SignRSA(data, keypair.Private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rawMessage, err := Verify(signedMessage, keypair.Public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rawMessage, data) {
+		t.Fatal("Verified data not equal to source data")
+	}
+
+	themisMessage := message.New(themisKeypair.Private, themisKeypair.Public)
+	rawMessage, err = themisMessage.Verify(signedMessage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rawMessage, data) {
+		t.Fatal("Verified data not equal to source data")
+	}
+	themisSigned, err := themisMessage.Sign(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rawMessage, err = Verify(themisSigned, keypair.Public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rawMessage, data) {
+		t.Fatal("Verified data not equal to source data")
+	}
+
+	// keys generated by Themis
+	themisRSA, err := keys.New(keys.TypeRSA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	themisSigned, err = message.New(themisRSA.Private, nil).Sign(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err := UnmarshalThemisRSAPublicKey(themisRSA.Public.Value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Verify(themisSigned, public); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSignRSAEmptyKey(t *testing.T) {
+	if _, err := SignRSA([]byte(`test`), nil); err != ErrEmptyPrivateKey {
+		t.Fatal("expected ErrEmptyPrivateKey", err)
+	}
+	if _, err := SignRSA([]byte(`test`), &PrivateRSAKey{}); err != ErrEmptyPrivateKey {
+		t.Fatal("expected ErrEmptyPrivateKey", err)
+	}
+}
+
+func TestVerifyRSAInvalid(t *testing.T) {
+	keypair, err := NewRSAKeyPairWithSize(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKeypair, err := NewECKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte(`test`)
+	signedMessage, err := SignRSA(data, keypair.Private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// algorithm chosen by message type so EC key can't be used for RSA signature and vice versa
+	if _, err := Verify(signedMessage, ecKeypair.Public); err != ErrVerify {
+		t.Fatal("expected ErrVerify", err)
+	}
+	ecSigned, err := Sign(data, ecKeypair.Private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Verify(ecSigned, keypair.Public); err != ErrVerify {
+		t.Fatal("expected ErrVerify", err)
+	}
+	signedMessage[signedMessageStaticOverhead] ^= 1
+	if _, err := Verify(signedMessage, keypair.Public); err != ErrVerify {
+		t.Fatal("expected ErrVerify", err)
+	}
+	otherKeypair, err := NewRSAKeyPairWithSize(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signedMessage[signedMessageStaticOverhead] ^= 1
+	if _, err := Verify(signedMessage, otherKeypair.Public); err != ErrVerify {
+		t.Fatal("expected ErrVerify", err)
+	}
+}
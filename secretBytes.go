@@ -0,0 +1,70 @@
+package gothemis
+
+import "errors"
+
+// ErrMemoryLockUnsupported returned by NewLockedSecretBytes on platforms without mlock
+var ErrMemoryLockUnsupported = errors.New("memory locking is not supported on this platform")
+
+// SecretBytes stores key material: symmetric keys, derived keys, shared secrets and serialized private keys. Destroy
+// wipes buffer so secret doesn't stay in memory after use. Buffers created by NewLockedSecretBytes also locked with
+// mlock to not be swapped to disk.
+//
+// Coverage is partial. PrivateECKey and PrivateRSAKey keep private values in math/big as crypto/ecdsa and crypto/rsa
+// require, their Zeroize wipes only words of these values. Copies made by standard library, like crypto/ecdh private
+// key cached by Secure Message or HMAC state inside golang.org/x/crypto/pbkdf2 used for passphrases, can't be wiped
+// and stay in memory until collected. Themis KDF keeps HMAC pads in SecretBytes and wipes them. Library allocates its own secrets
+// with NewSecretBytes, so nothing locked in memory unless caller uses NewLockedSecretBytes for own buffers
+type SecretBytes struct {
+	data   []byte
+	locked bool
+}
+
+func newSecretBytes(data []byte, locked bool) *SecretBytes {
+	secret := &SecretBytes{data: data, locked: locked}
+	trackSecretBytes(secret)
+	return secret
+}
+
+// NewSecretBytes return zeroed secret buffer with size
+func NewSecretBytes(size int) *SecretBytes {
+	return newSecretBytes(make([]byte, size), false)
+}
+
+// NewSecretBytesFromBytes return secret that owns data. data will be wiped by Destroy so caller must not use it
+// after that
+func NewSecretBytesFromBytes(data []byte) *SecretBytes {
+	return newSecretBytes(data, false)
+}
+
+// NewLockedSecretBytes return zeroed secret buffer with size allocated on own memory pages locked with mlock.
+// Returns ErrMemoryLockUnsupported on platforms without mlock and error from mlock if process exceeds RLIMIT_MEMLOCK
+func NewLockedSecretBytes(size int) (*SecretBytes, error) {
+	data, err := lockedAlloc(size)
+	if err != nil {
+		return nil, err
+	}
+	return newSecretBytes(data, true), nil
+}
+
+// Bytes return secret data. Returns nil after Destroy
+func (secret *SecretBytes) Bytes() []byte {
+	return secret.data
+}
+
+// Len return size of secret. Returns 0 after Destroy
+func (secret *SecretBytes) Len() int {
+	return len(secret.data)
+}
+
+// Destroy wipes secret and unlocks its memory. Safe to call several times
+func (secret *SecretBytes) Destroy() {
+	if secret == nil || secret.data == nil {
+		return
+	}
+	Zeroize(secret.data)
+	if secret.locked {
+		// memory already wiped so error may be ignored
+		lockedFree(secret.data)
+	}
+	secret.data = nil
+}
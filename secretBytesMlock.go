@@ -0,0 +1,33 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package gothemis
+
+import "syscall"
+
+// lockedAlloc return buffer with size on own anonymous memory pages locked with mlock. Own pages are required
+// because mlock isn't counted so munlock of one buffer would unlock other buffers on same page
+func lockedAlloc(size int) ([]byte, error) {
+	pageSize := syscall.Getpagesize()
+	mapSize := (size + pageSize - 1) / pageSize * pageSize
+	if mapSize == 0 {
+		mapSize = pageSize
+	}
+	data, err := syscall.Mmap(-1, 0, mapSize, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_ANON|syscall.MAP_PRIVATE)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Mlock(data); err != nil {
+		syscall.Munmap(data)
+		return nil, err
+	}
+	return data[:size], nil
+}
+
+// lockedFree unlocks and unmaps buffer returned by lockedAlloc
+func lockedFree(data []byte) error {
+	data = data[:cap(data)]
+	if err := syscall.Munlock(data); err != nil {
+		return err
+	}
+	return syscall.Munmap(data)
+}
@@ -0,0 +1,11 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package gothemis
+
+func lockedAlloc(size int) ([]byte, error) {
+	return nil, ErrMemoryLockUnsupported
+}
+
+func lockedFree(data []byte) error {
+	return ErrMemoryLockUnsupported
+}
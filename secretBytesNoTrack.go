@@ -0,0 +1,6 @@
+//go:build !gothemis_secrettrack
+
+package gothemis
+
+// trackSecretBytes does nothing in regular builds, see secretBytesTrack.go
+func trackSecretBytes(*SecretBytes) {}
@@ -0,0 +1,13 @@
+//go:build gothemis_secrettrack
+
+package gothemis
+
+// onNewSecretBytes called for every created SecretBytes if set. Exists only in builds with gothemis_secrettrack tag
+// used by tests that check that secrets destroyed: go test -tags gothemis_secrettrack
+var onNewSecretBytes func(*SecretBytes)
+
+func trackSecretBytes(secret *SecretBytes) {
+	if onNewSecretBytes != nil {
+		onNewSecretBytes(secret)
+	}
+}
@@ -0,0 +1,116 @@
+//go:build gothemis_secrettrack
+
+package gothemis
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// Tests of this file check that temporary secrets destroyed and run only with: go test -tags gothemis_secrettrack
+
+// collectSecrets return function that checks that all secrets created since call are destroyed and wiped
+func collectSecrets(t *testing.T) func() {
+	var secrets []*SecretBytes
+	var buffers [][]byte
+	onNewSecretBytes = func(secret *SecretBytes) {
+		secrets = append(secrets, secret)
+		buffers = append(buffers, secret.Bytes())
+	}
+	return func() {
+		onNewSecretBytes = nil
+		if len(secrets) == 0 {
+			t.Fatal("no secrets created")
+		}
+		for i, secret := range secrets {
+			if secret.Bytes() != nil || !bytes.Equal(buffers[i], make([]byte, len(buffers[i]))) {
+				t.Fatalf("secret %d not destroyed", i)
+			}
+		}
+	}
+}
+
+// failingWriter returns error on every write
+type failingWriter struct{}
+
+var errFailingWriter = errors.New("write failed")
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errFailingWriter
+}
+
+func TestSecretsDestroyedOnError(t *testing.T) {
+	data := []byte("some data")
+	key := []byte("some key")
+	encrypted, err := CellSealEncrypt(key, data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := collectSecrets(t)
+	if _, err := CellSealDecrypt([]byte("other key"), encrypted, nil); err != ErrCellAuthFailed {
+		t.Fatal("expected ErrCellAuthFailed", err)
+	}
+	check()
+
+	encrypted, err = cellSealEncryptWithPassphrase([]byte("passphrase"), data, nil, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check = collectSecrets(t)
+	if _, err := CellSealDecryptWithPassphrase("other passphrase", encrypted, nil); err != ErrCellAuthFailed {
+		t.Fatal("expected ErrCellAuthFailed", err)
+	}
+	check()
+
+	alice, err := NewECKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, err := NewECKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	smessage, err := NewSecureMessage(alice.Private, bob.Public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrapped, err := smessage.Wrap(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// bob unwraps message with incorrect peer key
+	smessage, err = NewSecureMessage(bob.Private, bob.Public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check = collectSecrets(t)
+	if _, err := smessage.Unwrap(wrapped); err == nil {
+		t.Fatal("expected error on incorrect peer key")
+	}
+	check()
+
+	acrastruct, err := CreateAcrastruct(data, bob.Public, []byte("zone"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	check = collectSecrets(t)
+	if _, err := DecryptAcrastruct(acrastruct, bob.Private, []byte("other zone")); err != ErrCellAuthFailed {
+		t.Fatal("expected ErrCellAuthFailed", err)
+	}
+	check()
+
+	check = collectSecrets(t)
+	writer, err := NewCellSealWriterWithChunkSize(key, nil, failingWriter{}, testStreamChunkSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// full chunk written when next data arrives
+	if _, err := writer.Write(make([]byte, testStreamChunkSize+1)); err != errFailingWriter {
+		t.Fatal("expected errFailingWriter", err)
+	}
+	if err := writer.Close(); err != errFailingWriter {
+		t.Fatal("expected errFailingWriter", err)
+	}
+	check()
+}
@@ -0,0 +1,96 @@
+package gothemis
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSecretBytesDestroy(t *testing.T) {
+	secret := NewSecretBytes(32)
+	data := secret.Bytes()
+	copy(data, bytes.Repeat([]byte{0xff}, 32))
+	secret.Destroy()
+	if !bytes.Equal(data, make([]byte, 32)) {
+		t.Fatal("secret not wiped")
+	}
+	if secret.Bytes() != nil || secret.Len() != 0 {
+		t.Fatal("destroyed secret still has data")
+	}
+	// repeated Destroy and Destroy of nil secret do nothing
+	secret.Destroy()
+	var nilSecret *SecretBytes
+	nilSecret.Destroy()
+}
+
+func TestLockedSecretBytes(t *testing.T) {
+	secret, err := NewLockedSecretBytes(100)
+	if err != nil {
+		t.Skip("memory locking unavailable:", err)
+	}
+	if secret.Len() != 100 {
+		t.Fatal("incorrect size of locked secret")
+	}
+	copy(secret.Bytes(), bytes.Repeat([]byte{0xff}, 100))
+	secret.Destroy()
+	if secret.Bytes() != nil {
+		t.Fatal("destroyed secret still has data")
+	}
+}
+
+func TestSecureCellDestroy(t *testing.T) {
+	key := []byte("some key")
+	seal, err := SealWithKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := seal.Encrypt([]byte("some data"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyData, pads := seal.key.Bytes(), seal.hmacPads.Bytes()
+	seal.Destroy()
+	// key and HMAC state derived from it wiped
+	if !bytes.Equal(keyData, make([]byte, len(keyData))) || !bytes.Equal(pads, make([]byte, len(pads))) {
+		t.Fatal("key of cell not wiped")
+	}
+	if _, err := seal.Encrypt([]byte("some data"), nil); err != ErrMissingKey {
+		t.Fatal("expected ErrMissingKey", err)
+	}
+	if _, err := seal.Decrypt(encrypted, nil); err != ErrMissingKey {
+		t.Fatal("expected ErrMissingKey", err)
+	}
+	tokenProtect, err := TokenProtectWithKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenProtect.Destroy()
+	if _, _, err := tokenProtect.Encrypt([]byte("some data"), nil); err != ErrMissingKey {
+		t.Fatal("expected ErrMissingKey", err)
+	}
+	contextImprint, err := ContextImprintWithKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	contextImprint.Destroy()
+	if _, err := contextImprint.Encrypt([]byte("some data"), []byte("context")); err != ErrMissingKey {
+		t.Fatal("expected ErrMissingKey", err)
+	}
+	if !bytes.Equal(key, []byte("some key")) {
+		t.Fatal("Destroy wiped caller's key")
+	}
+	passphrase, err := SealWithPassphrase("some passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	passphraseData := passphrase.passphrase.Bytes()
+	passphrase.Destroy()
+	if !bytes.Equal(passphraseData, make([]byte, len(passphraseData))) {
+		t.Fatal("passphrase of cell not wiped")
+	}
+	if _, err := passphrase.Encrypt([]byte("some data"), nil); err != ErrMissingPassphrase {
+		t.Fatal("expected ErrMissingPassphrase", err)
+	}
+	if _, err := passphrase.Decrypt([]byte("some data"), nil); err != ErrMissingPassphrase {
+		t.Fatal("expected ErrMissingPassphrase", err)
+	}
+}
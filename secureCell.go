@@ -0,0 +1,205 @@
+package gothemis
+
+import (
+	"crypto/sha256"
+	"hash"
+	"sync"
+)
+
+// Errors returned by Secure Cell objects on invalid input, same as in Themis
+var (
+	ErrMissingKey     = ThemisError("empty symmetric key for Secure Cell")
+	ErrMissingMessage = ThemisError("empty message for Secure Cell")
+	ErrMissingToken   = ThemisError("authentication token is required in Token Protect mode")
+)
+
+// copyKey return copy of key to not depend on caller's buffer
+func copyKey(key []byte) *SecretBytes {
+	secret := NewSecretBytes(len(key))
+	copy(secret.Bytes(), key)
+	return secret
+}
+
+// SecureCellSeal encrypts data in Seal mode with symmetric key. Safe for concurrent use
+type SecureCellSeal struct {
+	key *SecretBytes
+	// hmacPads stores HMAC-SHA256 inner and outer padded key blocks used by deriveKey. Kept here instead of in
+	// crypto/hmac instances so Destroy can wipe them
+	hmacPads *SecretBytes
+	// hashPool stores SHA-256 instances to avoid allocations on key derivation. Instances reset before return to pool
+	// so they don't keep state derived from key
+	hashPool sync.Pool
+}
+
+// SealWithKey return Secure Cell in Seal mode with key
+func SealWithKey(key []byte) (*SecureCellSeal, error) {
+	if len(key) == 0 {
+		return nil, ErrMissingKey
+	}
+	sc := &SecureCellSeal{key: copyKey(key), hmacPads: newHMACPads(key)}
+	sc.hashPool.New = func() interface{} {
+		return sha256.New()
+	}
+	return sc, nil
+}
+
+// deriveKey works like themisKDF with THEMIS_SYM_KDF_KEY_LABEL but computes HMAC-SHA256 with precomputed pads of
+// sc.key and writes result to output
+func (sc *SecureCellSeal) deriveKey(output *[sha256.Size]byte, messageLength []byte, context []byte) []byte {
+	// counter and separator after label, same as in themisKDF. Stored in output until inner hash is summed to not
+	// allocate separate buffer for hash.Hash.Write
+	counter := output[:4]
+	copy(counter, []byte{0, 0, 0, 1})
+	h := sc.hashPool.Get().(hash.Hash)
+	result := hmacSumWithPads(h, sc.hmacPads.Bytes(), output[:0], counter, THEMIS_SYM_KDF_KEY_LABEL, counter[:1],
+		messageLength, context)
+	sc.hashPool.Put(h)
+	return result
+}
+
+// Encrypt message with optional context
+func (sc *SecureCellSeal) Encrypt(message, context []byte) ([]byte, error) {
+	if len(message) == 0 {
+		return nil, ErrMissingMessage
+	}
+	return sc.SealAppend(nil, message, context)
+}
+
+// Decrypt message encrypted with same key and context
+func (sc *SecureCellSeal) Decrypt(encrypted, context []byte) ([]byte, error) {
+	if len(encrypted) == 0 {
+		return nil, ErrMissingMessage
+	}
+	return sc.OpenAppend(nil, encrypted, context)
+}
+
+// Destroy wipes copy of key and HMAC pads derived from it. Cell returns ErrMissingKey after Destroy. Not safe for
+// concurrent use with Encrypt and Decrypt
+func (sc *SecureCellSeal) Destroy() {
+	sc.key.Destroy()
+	sc.hmacPads.Destroy()
+}
+
+// SecureCellSealPassphrase encrypts data in Seal mode with passphrase
+type SecureCellSealPassphrase struct {
+	passphrase *SecretBytes
+}
+
+// SealWithPassphrase return Secure Cell in Seal mode with passphrase. Cell keeps copy of passphrase in SecretBytes,
+// string passed by caller can't be wiped
+func SealWithPassphrase(passphrase string) (*SecureCellSealPassphrase, error) {
+	if len(passphrase) == 0 {
+		return nil, ErrMissingPassphrase
+	}
+	return &SecureCellSealPassphrase{passphrase: NewSecretBytesFromBytes([]byte(passphrase))}, nil
+}
+
+// Encrypt message with optional context
+func (sc *SecureCellSealPassphrase) Encrypt(message, context []byte) ([]byte, error) {
+	if len(message) == 0 {
+		return nil, ErrMissingMessage
+	}
+	if sc.passphrase.Len() == 0 {
+		return nil, ErrMissingPassphrase
+	}
+	return cellSealEncryptWithPassphrase(sc.passphrase.Bytes(), message, context, THEMIS_AUTH_SYM_PBKDF2_ITERATIONS)
+}
+
+// Decrypt message encrypted with same passphrase and context
+func (sc *SecureCellSealPassphrase) Decrypt(encrypted, context []byte) ([]byte, error) {
+	if len(encrypted) == 0 {
+		return nil, ErrMissingMessage
+	}
+	if sc.passphrase.Len() == 0 {
+		return nil, ErrMissingPassphrase
+	}
+	return cellSealDecryptWithPassphrase(sc.passphrase.Bytes(), encrypted, context)
+}
+
+// Destroy wipes copy of passphrase. Cell returns ErrMissingPassphrase after Destroy
+func (sc *SecureCellSealPassphrase) Destroy() {
+	sc.passphrase.Destroy()
+}
+
+// SecureCellTokenProtect encrypts data in Token Protect mode with symmetric key
+type SecureCellTokenProtect struct {
+	key *SecretBytes
+}
+
+// TokenProtectWithKey return Secure Cell in Token Protect mode with key
+func TokenProtectWithKey(key []byte) (*SecureCellTokenProtect, error) {
+	if len(key) == 0 {
+		return nil, ErrMissingKey
+	}
+	return &SecureCellTokenProtect{key: copyKey(key)}, nil
+}
+
+// Encrypt message with optional context. Returns encrypted data with same length as message and authentication token
+func (sc *SecureCellTokenProtect) Encrypt(message, context []byte) ([]byte, []byte, error) {
+	if len(message) == 0 {
+		return nil, nil, ErrMissingMessage
+	}
+	if sc.key.Len() == 0 {
+		return nil, nil, ErrMissingKey
+	}
+	return CellTokenProtectEncrypt(sc.key.Bytes(), message, context)
+}
+
+// Decrypt message with authentication token
+func (sc *SecureCellTokenProtect) Decrypt(encrypted, token, context []byte) ([]byte, error) {
+	if len(encrypted) == 0 {
+		return nil, ErrMissingMessage
+	}
+	if len(token) == 0 {
+		return nil, ErrMissingToken
+	}
+	if sc.key.Len() == 0 {
+		return nil, ErrMissingKey
+	}
+	return CellTokenProtectDecrypt(sc.key.Bytes(), encrypted, token, context)
+}
+
+// Destroy wipes copy of key. Cell returns ErrMissingKey after Destroy
+func (sc *SecureCellTokenProtect) Destroy() {
+	sc.key.Destroy()
+}
+
+// SecureCellContextImprint encrypts data in Context Imprint mode with symmetric key
+type SecureCellContextImprint struct {
+	key *SecretBytes
+}
+
+// ContextImprintWithKey return Secure Cell in Context Imprint mode with key
+func ContextImprintWithKey(key []byte) (*SecureCellContextImprint, error) {
+	if len(key) == 0 {
+		return nil, ErrMissingKey
+	}
+	return &SecureCellContextImprint{key: copyKey(key)}, nil
+}
+
+// Encrypt message with required context
+func (sc *SecureCellContextImprint) Encrypt(message, context []byte) ([]byte, error) {
+	if len(message) == 0 {
+		return nil, ErrMissingMessage
+	}
+	if sc.key.Len() == 0 {
+		return nil, ErrMissingKey
+	}
+	return CellContextImprintEncrypt(sc.key.Bytes(), message, context)
+}
+
+// Decrypt message with required context
+func (sc *SecureCellContextImprint) Decrypt(encrypted, context []byte) ([]byte, error) {
+	if len(encrypted) == 0 {
+		return nil, ErrMissingMessage
+	}
+	if sc.key.Len() == 0 {
+		return nil, ErrMissingKey
+	}
+	return CellContextImprintDecrypt(sc.key.Bytes(), encrypted, context)
+}
+
+// Destroy wipes copy of key. Cell returns ErrMissingKey after Destroy
+func (sc *SecureCellContextImprint) Destroy() {
+	sc.key.Destroy()
+}
@@ -0,0 +1,140 @@
+package gothemis
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+)
+
+// grow return slice with len(dst)+n length and dst as prefix. Allocates only if dst has no enough capacity
+func grow(dst []byte, n int) []byte {
+	if cap(dst)-len(dst) >= n {
+		return dst[:len(dst)+n]
+	}
+	output := make([]byte, len(dst)+n)
+	copy(output, dst)
+	return output
+}
+
+// newAESGCM return AES-GCM with kdfKey. AES key schedule depends on key derived from message length and context so
+// it can't be cached between messages
+func newAESGCM(kdfKey []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(kdfKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// SealAppend encrypts plaintext in Seal mode with optional context and appends result to dst. Output compatible
+// with CellSealEncrypt. Output buffer allocated only if dst has no enough capacity for header, ciphertext and auth
+// tag, but AES-GCM state allocated for each message because key derived from message length and context. On error
+// returns dst unchanged without writes after its length. plaintext must not overlap with dst
+func (sc *SecureCellSeal) SealAppend(dst, plaintext, context []byte) ([]byte, error) {
+	if len(plaintext) == 0 {
+		return dst, ErrMissingMessage
+	}
+	if sc.key.Len() == 0 {
+		return dst, ErrMissingKey
+	}
+	var iv [THEMIS_AUTH_SYM_IV_LENGTH]byte
+	if n, err := rand.Read(iv[:]); err != nil {
+		return dst, err
+	} else if n != THEMIS_AUTH_SYM_IV_LENGTH {
+		return dst, errors.New("can't read enough random data for IV")
+	}
+	var messageLength [4]byte
+	binary.LittleEndian.PutUint32(messageLength[:], uint32(len(plaintext)))
+	var kdfKeyBuf [sha256.Size]byte
+	kdfKey := sc.deriveKey(&kdfKeyBuf, messageLength[:], context)
+	aesGCM, err := newAESGCM(kdfKey)
+	Zeroize(kdfKeyBuf[:])
+	if err != nil {
+		return dst, err
+	}
+
+	start := len(dst)
+	// reserve THEMIS_AUTH_SYM_AUTH_TAG_LENGTH more because AEAD appends tag after ciphertext
+	output := grow(dst, AuthSymMessageHeaderSize+len(plaintext)+THEMIS_AUTH_SYM_AUTH_TAG_LENGTH)
+	header := output[start : start+AuthSymMessageHeaderSize]
+	binary.LittleEndian.PutUint32(header[0:4], THEMIS_AUTH_SYM_ALG)
+	binary.LittleEndian.PutUint32(header[4:8], THEMIS_AUTH_SYM_IV_LENGTH)
+	binary.LittleEndian.PutUint32(header[8:12], THEMIS_AUTH_SYM_AUTH_TAG_LENGTH)
+	copy(header[12:16], messageLength[:])
+	copy(header[authSymMessageHeaderFieldsSize:], iv[:])
+	ciphertextStart := start + AuthSymMessageHeaderSize
+	sealed := aesGCM.Seal(output[ciphertextStart:ciphertextStart], iv[:], plaintext, context)
+	// move tag from the end to header
+	copy(header[authSymMessageHeaderFieldsSize+THEMIS_AUTH_SYM_IV_LENGTH:], sealed[len(plaintext):])
+	return output[:ciphertextStart+len(plaintext)], nil
+}
+
+// OpenAppend decrypts ciphertext in Seal mode with optional context and appends result to dst. Output buffer
+// allocated only if dst has no enough capacity for plaintext, but AES-GCM state and buffer for ciphertext with auth
+// tag allocated for each message. On error returns dst unchanged without writes after its length
+func (sc *SecureCellSeal) OpenAppend(dst, ciphertext, context []byte) ([]byte, error) {
+	if len(ciphertext) == 0 {
+		return dst, ErrMissingMessage
+	}
+	if sc.key.Len() == 0 {
+		return dst, ErrMissingKey
+	}
+	if len(ciphertext) < authSymMessageHeaderFieldsSize {
+		return dst, ErrCellTruncated
+	}
+	alg := binary.LittleEndian.Uint32(ciphertext[0:4])
+	if err := validateAuthSymAlg(alg); err != nil {
+		return dst, err
+	}
+	if alg&SOTER_SYM_KDF_MASK != SOTER_SYM_NOKDF {
+		return dst, ErrCellBadAlgorithm
+	}
+	if binary.LittleEndian.Uint32(ciphertext[4:8]) != THEMIS_AUTH_SYM_IV_LENGTH ||
+		binary.LittleEndian.Uint32(ciphertext[8:12]) != THEMIS_AUTH_SYM_AUTH_TAG_LENGTH {
+		return dst, ErrCellMalformed
+	}
+	if len(ciphertext) < AuthSymMessageHeaderSize {
+		return dst, ErrCellTruncated
+	}
+	var messageLength AuthTagFieldLength
+	copy(messageLength[:], ciphertext[12:16])
+	encrypted := ciphertext[AuthSymMessageHeaderSize:]
+	if err := validateMessageLength(messageLength, encrypted); err != nil {
+		return dst, err
+	}
+	iv := ciphertext[authSymMessageHeaderFieldsSize : authSymMessageHeaderFieldsSize+THEMIS_AUTH_SYM_IV_LENGTH]
+	tag := ciphertext[authSymMessageHeaderFieldsSize+THEMIS_AUTH_SYM_IV_LENGTH : AuthSymMessageHeaderSize]
+
+	keyLength, err := soterAlgKeyLength(alg)
+	if err != nil {
+		return dst, err
+	}
+	// AEAD expects tag after ciphertext so join them in own buffer and decrypt in place. dst isn't used for it
+	// because failed Open may write to its buffer
+	buffer := make([]byte, len(encrypted)+len(tag))
+	defer Zeroize(buffer)
+	var legacyMessageLength [8]byte
+	binary.LittleEndian.PutUint64(legacyMessageLength[:], uint64(len(encrypted)))
+	// try current KDF context and then legacy one like Themis does
+	for _, kdfContext := range [][]byte{messageLength[:], legacyMessageLength[:]} {
+		var kdfKeyBuf [sha256.Size]byte
+		kdfKey := sc.deriveKey(&kdfKeyBuf, kdfContext, context)
+		aesGCM, err := newAESGCM(kdfKey[:keyLength])
+		Zeroize(kdfKeyBuf[:])
+		if err != nil {
+			return dst, err
+		}
+		copy(buffer, encrypted)
+		copy(buffer[len(encrypted):], tag)
+		if plaintext, err := aesGCM.Open(buffer[:0], iv, buffer, context); err == nil {
+			start := len(dst)
+			output := grow(dst, len(plaintext))
+			copy(output[start:], plaintext)
+			return output, nil
+		}
+	}
+	return dst, ErrCellAuthFailed
+}
@@ -0,0 +1,229 @@
+package gothemis
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+func TestSecureCellSealAppend(t *testing.T) {
+	key := make([]byte, 32)
+	message := make([]byte, 100)
+	context := make([]byte, 100)
+	rand.Read(key)
+	sc, err := SealWithKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prefix := []byte("prefix")
+	for i := 0; i < 1000; i++ {
+		rand.Read(message)
+		rand.Read(context)
+		encrypted, err := sc.SealAppend(append([]byte{}, prefix...), message, context)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(encrypted[:len(prefix)], prefix) {
+			t.Fatal("prefix changed")
+		}
+		encrypted = encrypted[len(prefix):]
+		data, err := CellSealDecrypt(key, encrypted, context)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, message) {
+			t.Fatal("data not equal")
+		}
+
+		encrypted, err = CellSealEncrypt(key, message, context)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err = sc.OpenAppend(append([]byte{}, prefix...), encrypted, context)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data[:len(prefix)], prefix) || !bytes.Equal(data[len(prefix):], message) {
+			t.Fatal("data not equal")
+		}
+		if _, err := sc.OpenAppend(nil, encrypted, nil); err != ErrCellAuthFailed {
+			t.Fatal("expected ErrCellAuthFailed")
+		}
+		if _, err := sc.OpenAppend(nil, encrypted[:len(encrypted)-1], context); err != ErrCellTruncated {
+			t.Fatal("expected ErrCellTruncated")
+		}
+	}
+	for _, keyLength := range []uint32{SOTER_SYM_128_KEY_LENGTH, SOTER_SYM_192_KEY_LENGTH} {
+		encrypted, err := CellSealEncryptWithKeyLength(key, message, context, keyLength)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := sc.OpenAppend(nil, encrypted, context)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, message) {
+			t.Fatal("data not equal")
+		}
+	}
+}
+
+func TestSecureCellSealAppendErrors(t *testing.T) {
+	sc, err := SealWithKey([]byte("key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := sc.SealAppend(nil, []byte("message"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// spare capacity filled with marker to check that failed call doesn't write to it
+	buffer := bytes.Repeat([]byte{0xff}, 1024)
+	dst := append(buffer[:0], "prefix"...)
+	checkUnchanged := func(output []byte) {
+		if !bytes.Equal(output, []byte("prefix")) || &output[0] != &dst[0] {
+			t.Fatal("dst changed on error")
+		}
+		if !bytes.Equal(buffer[len(dst):], bytes.Repeat([]byte{0xff}, len(buffer)-len(dst))) {
+			t.Fatal("spare capacity of dst changed on error")
+		}
+	}
+	output, err := sc.OpenAppend(dst, encrypted, []byte("other context"))
+	if err != ErrCellAuthFailed {
+		t.Fatal("expected ErrCellAuthFailed", err)
+	}
+	checkUnchanged(output)
+	output, err = sc.OpenAppend(dst, encrypted[:len(encrypted)-1], nil)
+	if err != ErrCellTruncated {
+		t.Fatal("expected ErrCellTruncated", err)
+	}
+	checkUnchanged(output)
+	output, err = sc.SealAppend(dst, nil, nil)
+	if err != ErrMissingMessage {
+		t.Fatal("expected ErrMissingMessage", err)
+	}
+	checkUnchanged(output)
+	sc.Destroy()
+	output, err = sc.SealAppend(dst, []byte("message"), nil)
+	if err != ErrMissingKey {
+		t.Fatal("expected ErrMissingKey", err)
+	}
+	checkUnchanged(output)
+}
+
+// TestSecureCellSealKeyLengths checks HMAC key padding in deriveKey for keys shorter, equal and longer than block
+func TestSecureCellSealKeyLengths(t *testing.T) {
+	message := []byte("some data")
+	for _, length := range []int{1, 32, 63, 64, 65, 200} {
+		key := make([]byte, length)
+		rand.Read(key)
+		sc, err := SealWithKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		encrypted, err := sc.Encrypt(message, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := CellSealDecrypt(key, encrypted, nil)
+		if err != nil {
+			t.Fatalf("key of %d bytes: %v", length, err)
+		}
+		if !bytes.Equal(data, message) {
+			t.Fatal("data not equal")
+		}
+	}
+}
+
+func TestSecureCellSealAppendAllocations(t *testing.T) {
+	sc, err := SealWithKey([]byte("key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := make([]byte, 100)
+	buffer := make([]byte, 0, 1024)
+	encrypted, err := sc.SealAppend(nil, message, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendAllocs := testing.AllocsPerRun(100, func() {
+		if _, err := sc.SealAppend(buffer[:0], message, nil); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := sc.OpenAppend(buffer[:0], encrypted, nil); err != nil {
+			t.Fatal(err)
+		}
+	})
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, err := CellSealEncrypt([]byte("key"), message, nil); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := CellSealDecrypt([]byte("key"), encrypted, nil); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if appendAllocs >= allocs {
+		t.Fatalf("SealAppend/OpenAppend allocate %v times, CellSealEncrypt/CellSealDecrypt %v", appendAllocs, allocs)
+	}
+}
+
+func BenchmarkSealAppend(b *testing.B) {
+	sc, err := SealWithKey([]byte("key"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	message := []byte("message")
+	buffer := make([]byte, 0, 1024)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := sc.SealAppend(buffer[:0], message, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkOpenAppend(b *testing.B) {
+	sc, err := SealWithKey([]byte("key"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	encrypted, err := sc.SealAppend(nil, []byte("message"), nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	buffer := make([]byte, 0, 1024)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := sc.OpenAppend(buffer[:0], encrypted, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCellSealEncrypt(b *testing.B) {
+	key := []byte("key")
+	message := []byte("message")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := CellSealEncrypt(key, message, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCellSealDecrypt(b *testing.B) {
+	key := []byte("key")
+	encrypted, err := CellSealEncrypt(key, []byte("message"), nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := CellSealDecrypt(key, encrypted, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package gothemis
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/cossacklabs/themis/gothemis/cell"
+	"github.com/cossacklabs/themis/gothemis/keys"
+)
+
+func TestSecureCellSeal(t *testing.T) {
+	key := make([]byte, 32)
+	message := make([]byte, 100)
+	context := make([]byte, 100)
+	rand.Read(key)
+	rand.Read(message)
+	rand.Read(context)
+	sc, err := SealWithKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	themisCell, err := cell.SealWithKey(&keys.SymmetricKey{Value: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := sc.Encrypt(message, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := themisCell.Decrypt(encrypted, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, message) {
+		t.Fatal("data not equal")
+	}
+	encrypted, err = themisCell.Encrypt(message, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err = sc.Decrypt(encrypted, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, message) {
+		t.Fatal("data not equal")
+	}
+}
+
+func TestSecureCellTokenProtect(t *testing.T) {
+	key := make([]byte, 32)
+	message := make([]byte, 100)
+	context := make([]byte, 100)
+	rand.Read(key)
+	rand.Read(message)
+	rand.Read(context)
+	sc, err := TokenProtectWithKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	themisCell, err := cell.TokenProtectWithKey(&keys.SymmetricKey{Value: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, token, err := sc.Encrypt(message, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := themisCell.Decrypt(encrypted, token, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, message) {
+		t.Fatal("data not equal")
+	}
+	encrypted, token, err = themisCell.Encrypt(message, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err = sc.Decrypt(encrypted, token, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, message) {
+		t.Fatal("data not equal")
+	}
+	if _, err := sc.Decrypt(encrypted, nil, context); err != ErrMissingToken {
+		t.Fatal("expected ErrMissingToken")
+	}
+}
+
+func TestSecureCellContextImprint(t *testing.T) {
+	key := make([]byte, 32)
+	message := make([]byte, 100)
+	context := make([]byte, 100)
+	rand.Read(key)
+	rand.Read(message)
+	rand.Read(context)
+	sc, err := ContextImprintWithKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	themisCell, err := cell.ContextImprintWithKey(&keys.SymmetricKey{Value: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := sc.Encrypt(message, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	themisEncrypted, err := themisCell.Encrypt(message, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encrypted, themisEncrypted) {
+		t.Fatal("encrypted data not equal to themis output")
+	}
+	data, err := sc.Decrypt(encrypted, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, message) {
+		t.Fatal("data not equal")
+	}
+	if _, err := sc.Encrypt(message, nil); err != ErrMissingContext {
+		t.Fatal("expected ErrMissingContext")
+	}
+}
+
+func TestSecureCellInvalidInput(t *testing.T) {
+	if _, err := SealWithKey(nil); err != ErrMissingKey {
+		t.Fatal("expected ErrMissingKey")
+	}
+	if _, err := TokenProtectWithKey([]byte{}); err != ErrMissingKey {
+		t.Fatal("expected ErrMissingKey")
+	}
+	if _, err := ContextImprintWithKey(nil); err != ErrMissingKey {
+		t.Fatal("expected ErrMissingKey")
+	}
+	if _, err := SealWithPassphrase(""); err != ErrMissingPassphrase {
+		t.Fatal("expected ErrMissingPassphrase")
+	}
+
+	key := []byte("key")
+	seal, err := SealWithKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := seal.Encrypt(nil, nil); err != ErrMissingMessage {
+		t.Fatal("expected ErrMissingMessage")
+	}
+	if _, err := seal.Decrypt([]byte{}, nil); err != ErrMissingMessage {
+		t.Fatal("expected ErrMissingMessage")
+	}
+	tokenProtect, err := TokenProtectWithKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := tokenProtect.Encrypt(nil, nil); err != ErrMissingMessage {
+		t.Fatal("expected ErrMissingMessage")
+	}
+	contextImprint, err := ContextImprintWithKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := contextImprint.Encrypt(nil, []byte("context")); err != ErrMissingMessage {
+		t.Fatal("expected ErrMissingMessage")
+	}
+	passphrase, err := SealWithPassphrase("passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := passphrase.Encrypt(nil, nil); err != ErrMissingMessage {
+		t.Fatal("expected ErrMissingMessage")
+	}
+
+	// key copied and not affected by changes of caller's buffer
+	encrypted, err := seal.Encrypt([]byte("message"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Zeroize(key)
+	if _, err := seal.Decrypt(encrypted, nil); err != nil {
+		t.Fatal(err)
+	}
+}
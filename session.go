@@ -1,6 +1,8 @@
 package gothemis
 
 import (
+	"crypto"
+	"crypto/ecdsa"
 	"encoding/binary"
 	"errors"
 )
@@ -40,15 +42,19 @@ func validateCallback(c Callback) error {
 type secureSession struct {
 	id          []byte
 	ecdhKeypair *KeyPair
-	signKey     *PrivateECKey
+	signKey     crypto.Signer
 	peerPublicKey *PublicECKey
 	callback    Callback
 }
 
-func newSecureSession(id []byte, signKey *PrivateECKey, publicKey *PublicECKey, callback Callback) (*secureSession, error) {
-	if signKey == nil {
+func newSecureSession(id []byte, signKey crypto.Signer, publicKey *PublicECKey, callback Callback) (*secureSession, error) {
+	if isEmptySigner(signKey) {
 		return nil, ErrEmptyPrivateKey
 	}
+	// Secure Session uses only ECDSA signatures
+	if _, ok := signKey.Public().(*ecdsa.PublicKey); !ok {
+		return nil, ErrUnsupportedSigner
+	}
 	if err := validateCallback(callback); err != nil {
 		return nil, err
 	}
@@ -110,7 +116,7 @@ func (session *secureSession) ConnectRequest() ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	signature, err := signECDSA(pubkeyBytes, session.signKey.private)
+	signature, err := signECDSA(pubkeyBytes, session.signKey)
 	if err != nil {
 		return nil, err
 	}
@@ -161,7 +167,9 @@ func (session *secureSession) ConnectRequest() ([]byte, error) {
 	return output, nil
 }
 
-func NewSecureSession(id []byte, signatureKey *PrivateECKey, publicKey *PublicECKey, callback Callback) (SecureSession, error) {
+// NewSecureSession return Secure Session that signs with signatureKey. signatureKey may be *PrivateECKey or any
+// crypto.Signer with ECDSA key, e.g. key kept outside of process
+func NewSecureSession(id []byte, signatureKey crypto.Signer, publicKey *PublicECKey, callback Callback) (SecureSession, error) {
 	s, err := newSecureSession(id, signatureKey, publicKey, callback)
 	if err != nil {
 		return nil, err
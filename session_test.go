@@ -89,3 +89,37 @@ func TestSecureSession_ConnectRequest(t *testing.T) {
 	}
 
 }
+
+func TestSecureSessionSigner(t *testing.T) {
+	kp, err := NewECKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	kp2, err := NewECKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := &testSigner{signer: kp.Private}
+	goSession, err := NewSecureSession([]byte(`test`), signer, kp2.Public, cb{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := goSession.ConnectRequest(); err != nil {
+		t.Fatal(err)
+	}
+	if signer.calls != 1 {
+		t.Fatal("connect request not signed by signer")
+	}
+
+	rsaKeypair, err := NewRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewSecureSession([]byte(`test`), rsaKeypair.Private, kp2.Public, cb{}); err != ErrUnsupportedSigner {
+		t.Fatal("expected ErrUnsupportedSigner", err)
+	}
+	var nilKey *PrivateECKey
+	if _, err := NewSecureSession([]byte(`test`), nilKey, kp2.Public, cb{}); err != ErrEmptyPrivateKey {
+		t.Fatal("expected ErrEmptyPrivateKey", err)
+	}
+}
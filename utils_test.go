@@ -32,3 +32,40 @@ func TestCompressNISTPublicKey(t *testing.T) {
 		}
 	}
 }
+
+func TestUncompressNISTPublicKeyInvalid(t *testing.T) {
+	_, x, y, err := elliptic.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressed := CompressNISTPublicKey(elliptic.P256(), x, y)
+	notOnCurve := append([]byte{}, compressed...)
+	// x = p - 1 has no square root for P-256
+	copy(notOnCurve[1:], elliptic.P256().Params().P.Bytes())
+	notOnCurve[len(notOnCurve)-1]--
+	invalidPrefix := append([]byte{}, compressed...)
+	invalidPrefix[0] = 4
+	for _, data := range [][]byte{nil, compressed[:1], compressed[:len(compressed)-1], notOnCurve, invalidPrefix} {
+		if _, _, err := UncompressNISTPublicKey(elliptic.P256(), data); err != ErrInvalidCompressedPointData {
+			t.Fatal("expected ErrInvalidCompressedPointData", err)
+		}
+	}
+}
+
+func BenchmarkUncompressNISTPublicKey(b *testing.B) {
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		b.Run(curve.Params().Name, func(b *testing.B) {
+			_, x, y, err := elliptic.GenerateKey(curve, rand.Reader)
+			if err != nil {
+				b.Fatal(err)
+			}
+			compressed := CompressNISTPublicKey(curve, x, y)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, _, err := UncompressNISTPublicKey(curve, compressed); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}